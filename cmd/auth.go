@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage your OpenRouter API key",
+	Long:  "Store, inspect and remove the OpenRouter API key. Keys are kept in the Secret Service keyring when available, otherwise in an encrypted file readable only by you.",
+}
+
+var authUseFile bool
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store your API key",
+	Long:  "Prompt for an OpenRouter API key and store it securely. The key can also be piped on stdin.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		key, err := readAPIKey()
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error reading API key: ") + err.Error())
+			os.Exit(1)
+		}
+		if key == "" {
+			fmt.Println(pkg.RedBold("Error: ") + "no API key given")
+			os.Exit(1)
+		}
+		if err := validateSecretValue(config, key); err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		backend, err := storeAPIKey(config.GetProfile(), key, authUseFile)
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error storing API key: ") + err.Error())
+			os.Exit(1)
+		}
+
+		if backend == backendKeyring {
+			fmt.Println(pkg.GreenBold(pkg.Icon("✅") + "API key stored in the Secret Service keyring"))
+		} else {
+			path, _ := getCredentialsPath(config.GetProfile())
+			fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"API key stored in encrypted file ") + pkg.YellowBold(path))
+		}
+
+		if config.userLayer().OpenRouterKey != "" {
+			if err := config.removePlaintextKey(); err != nil {
+				fmt.Println(pkg.YellowBold("Warning: ") + "could not remove the plain-text key from config.json: " + err.Error())
+			} else {
+				fmt.Println(pkg.Cyan("Removed the plain-text key from config.json"))
+			}
+		}
+		if os.Getenv("PHOTON_OPEN_ROUTER_KEY") != "" {
+			fmt.Println(pkg.YellowBold("Note: ") + "PHOTON_OPEN_ROUTER_KEY is set and takes precedence over the stored key")
+		}
+	},
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show where your API key comes from",
+	Long:  "Show which API key is in effect, where it is stored, and whether the keyring is available",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		fmt.Println(pkg.CyanBold(pkg.Icon("🔑") + "Authentication:"))
+		fmt.Println()
+
+		keyring := "not available (needs secret-tool and a D-Bus session)"
+		if keyringAvailable() {
+			keyring = "available"
+		}
+		printConfigLine("profile", config.GetProfile(), "")
+		printConfigLine("keyring", keyring, "")
+
+		if config.credentialsErr != nil {
+			fmt.Println(pkg.RedBold("Refused: ") + config.credentialsErr.Error())
+		}
+
+		if config.OpenRouterKey == "" {
+			printConfigLine("api key", "(not set)", "")
+			fmt.Println()
+			fmt.Println(pkg.Muted("Run 'ptn auth login' to store a key"))
+			os.Exit(1)
+		}
+
+		printConfigLine("api key", maskSecret(config.OpenRouterKey), "")
+		printConfigLine("source", config.describeLayer("openrouter_key"), "")
+		if config.Origin("openrouter_key") == originUser {
+			fmt.Println()
+			fmt.Println(pkg.YellowBold("Warning: ") + "the key is stored in plain text; run 'ptn auth login' to move it to secure storage")
+		}
+	},
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove your stored API key",
+	Long:  "Remove the API key from the keyring, the encrypted credentials file and the user config file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		removed, err := deleteAPIKey(config.GetProfile())
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error removing API key: ") + err.Error())
+			os.Exit(1)
+		}
+		if config.userLayer().OpenRouterKey != "" {
+			if err := config.removePlaintextKey(); err != nil {
+				fmt.Println(pkg.RedBold("Error removing API key: ") + err.Error())
+				os.Exit(1)
+			}
+			removed = append(removed, "config.json")
+		}
+
+		if len(removed) == 0 {
+			fmt.Println(pkg.YellowBold("No stored API key found"))
+		} else {
+			fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"Removed API key from: ") + strings.Join(removed, ", "))
+		}
+		if os.Getenv("PHOTON_OPEN_ROUTER_KEY") != "" {
+			fmt.Println(pkg.YellowBold("Note: ") + "PHOTON_OPEN_ROUTER_KEY is still set in your environment")
+		}
+	},
+}
+
+// readAPIKey prompts for the API key without echoing it, or reads it from piped stdin
+func readAPIKey() (string, error) {
+	if term.IsTerminal(os.Stdin.Fd()) {
+		fmt.Print("OpenRouter API key: ")
+		key, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Println()
+		return strings.TrimSpace(string(key)), err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func init() {
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authLogoutCmd)
+
+	authLoginCmd.Flags().BoolVar(&authUseFile, "file", false, "Store the key in the encrypted file even if a keyring is available")
+}
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// Limits on retrying failed batch items
+const (
+	maxBatchBackoff   = time.Minute
+	firstBatchBackoff = 2 * time.Second
+)
+
+var (
+	batchConcurrency int
+	batchOut         string
+	batchRate        int
+	batchRetries     int
+	batchRestart     bool
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <queries.txt|queries.csv>",
+	Short: "Research every query in a file",
+	Long: `Research every query in a file and write one JSON result per line.
+
+Text files hold one query per line; blank lines and lines starting with # are skipped.
+CSV files use a "query" column, or the first column without a header, and may set
+"model" and "template" per row. Use - to read queries from stdin.
+
+Results already in the output file are skipped, so an interrupted run picks up where it
+stopped. Rate limited requests are retried after the wait the API asks for.
+
+Results are only added to the history file when 'history' is set to true.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+		if err := config.Validate(); err != nil {
+			fmt.Println(pkg.RedBold("Configuration error: ") + err.Error())
+			os.Exit(1)
+		}
+		client, err := config.NewClient()
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+		if batchConcurrency < 1 {
+			fmt.Println(pkg.RedBold("Error: ") + "--concurrency must be at least 1")
+			os.Exit(1)
+		}
+
+		queries, err := readBatchFile(args[0])
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error reading queries: ") + err.Error())
+			os.Exit(1)
+		}
+		if len(queries) == 0 {
+			fmt.Println(pkg.YellowBold("No queries found in ") + args[0])
+			return
+		}
+
+		outPath := batchOut
+		if outPath == "" {
+			outPath = defaultBatchOut(args[0])
+		}
+
+		run := &batchRun{
+			config:  config,
+			queries: queries,
+			pacer:   newPacer(batchRate),
+			retries: batchRetries,
+		}
+		if err := run.prepare(client, outPath, batchRestart); err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+		defer run.out.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		if term.IsTerminal(os.Stdout.Fd()) {
+			program := tea.NewProgram(pkg.NewBatchProgress(run.items()))
+			run.notify = program.Send
+			go func() {
+				run.execute(ctx, batchConcurrency)
+				program.Send(pkg.BatchDoneMsg{})
+			}()
+
+			finalModel, err := program.Run()
+			if err != nil {
+				fmt.Println(pkg.RedBold("could not run program: ") + err.Error())
+				os.Exit(1)
+			}
+			if progress, ok := finalModel.(pkg.BatchProgressModel); ok && !progress.Finished() {
+				// Stopped early: cancel the queries in flight and wait for them to settle
+				cancel()
+				run.wait()
+			}
+		} else {
+			run.notify = run.printProgress
+			for i, item := range run.items() {
+				if item.Status == pkg.BatchFailed {
+					run.printProgress(pkg.BatchItemMsg{Index: i, Status: item.Status, Detail: item.Detail})
+				}
+			}
+			run.execute(ctx, batchConcurrency)
+		}
+
+		done, failed, skipped, pending := run.tally()
+		fmt.Printf("%s %d done, %d failed, %d already done", pkg.CyanBold("Batch finished:"), done, failed, skipped)
+		if pending > 0 {
+			fmt.Printf(", %d not run", pending)
+		}
+		fmt.Println()
+		fmt.Println(pkg.Muted("Results: " + outPath))
+		if failed > 0 || pending > 0 {
+			fmt.Println(pkg.Muted("Run the same command again to retry the rest"))
+			os.Exit(1)
+		}
+	},
+}
+
+// batchQuery is one query read from a batch file; empty model and template use the config
+type batchQuery struct {
+	Line     int
+	Query    string
+	Model    string
+	Template string
+}
+
+// batchResult is one line of the batch output file
+type batchResult struct {
+	Line       int       `json:"line"`
+	Query      string    `json:"query"`
+	Model      string    `json:"model"`
+	Template   string    `json:"template"`
+	Time       time.Time `json:"time"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	*pkg.FormattedResponse
+}
+
+// key identifies a query with its effective model and template, for resuming
+func (r batchResult) key() string {
+	return r.Query + "\x00" + r.Model + "\x00" + r.Template
+}
+
+// readBatchFile reads queries from a text or CSV file, or from stdin for "-"
+func readBatchFile(path string) ([]batchQuery, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readBatchCSV(in)
+	}
+
+	var queries []batchQuery
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		queries = append(queries, batchQuery{Line: line, Query: text})
+	}
+	return queries, scanner.Err()
+}
+
+// readBatchCSV reads queries from CSV, using the query, model and template columns of a
+// header row when there is one
+func readBatchCSV(in io.Reader) ([]batchQuery, error) {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	// Lines are where each record starts, so comments and quoted line breaks do not shift them
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{"query": 0, "model": -1, "template": -1}
+	header := false
+	for i, name := range records[0] {
+		if _, ok := columns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+			header = true
+		}
+	}
+
+	field := func(record []string, column int) string {
+		if column < 0 || column >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[column])
+	}
+
+	var queries []batchQuery
+	for i, record := range records {
+		if header && i == 0 {
+			continue
+		}
+		if query := field(record, columns["query"]); query != "" {
+			queries = append(queries, batchQuery{
+				Line:     lines[i],
+				Query:    query,
+				Model:    field(record, columns["model"]),
+				Template: field(record, columns["template"]),
+			})
+		}
+	}
+	return queries, nil
+}
+
+// defaultBatchOut names the output file after the input file
+func defaultBatchOut(path string) string {
+	if path == "-" {
+		return "results.jsonl"
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".results.jsonl"
+}
+
+// batchRun is the state of one batch run
+type batchRun struct {
+	config  *Config
+	queries []batchQuery
+	pacer   *pacer
+	retries int
+
+	// clients holds the client for each query, or nil when its model or template is invalid
+	clients []*pkg.Client
+	// status tracks each query and is guarded by mu; errs holds why a query failed
+	mu     sync.Mutex
+	status []int
+	errs   []error
+
+	out    *os.File
+	outMu  sync.Mutex
+	notify func(tea.Msg)
+	wg     sync.WaitGroup
+}
+
+// prepare resolves each query's client, skips queries already answered in the output
+// file and opens the file for appending
+func (r *batchRun) prepare(client *pkg.Client, outPath string, restart bool) error {
+	r.clients = make([]*pkg.Client, len(r.queries))
+	r.status = make([]int, len(r.queries))
+	r.errs = make([]error, len(r.queries))
+
+	done := make(map[string]bool)
+	if !restart {
+		previous, err := readBatchResults(outPath)
+		if err != nil {
+			return err
+		}
+		for _, result := range previous {
+			if result.Error == "" {
+				done[result.key()] = true
+			}
+		}
+	}
+
+	for i, query := range r.queries {
+		queryClient, err := client.With(pkg.WithModel(query.Model), pkg.WithTemplate(query.Template))
+		if err != nil {
+			r.status[i], r.errs[i] = pkg.BatchFailed, err
+			continue
+		}
+		r.clients[i] = queryClient
+		key := batchResult{Query: query.Query, Model: queryClient.Model(), Template: queryClient.Template()}.key()
+		if done[key] {
+			r.status[i] = pkg.BatchSkipped
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if restart {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+	out, err := os.OpenFile(outPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	r.out = out
+	return nil
+}
+
+// readBatchResults reads the results of a previous run, if there was one
+func readBatchResults(path string) ([]batchResult, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var results []batchResult
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var result batchResult
+		// A line cut short by an interrupted run is simply retried
+		if json.Unmarshal(scanner.Bytes(), &result) == nil {
+			results = append(results, result)
+		}
+	}
+	return results, scanner.Err()
+}
+
+// items returns the initial state of every query for the progress view
+func (r *batchRun) items() []pkg.BatchItem {
+	items := make([]pkg.BatchItem, len(r.queries))
+	for i, query := range r.queries {
+		items[i] = pkg.BatchItem{Query: query.Query, Status: r.status[i]}
+		if r.errs[i] != nil {
+			items[i].Detail = r.errs[i].Error()
+		}
+	}
+	return items
+}
+
+// execute researches every pending query with a bounded pool of workers
+func (r *batchRun) execute(ctx context.Context, workers int) {
+	jobs := make(chan int)
+	for range workers {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			for i := range jobs {
+				r.research(ctx, i)
+			}
+		}()
+	}
+
+	for i := range r.queries {
+		if r.status[i] != pkg.BatchPending {
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	r.wg.Wait()
+}
+
+// wait blocks until every worker has stopped
+func (r *batchRun) wait() {
+	r.wg.Wait()
+}
+
+// research runs one query, retrying when the API is rate limited or temporarily failing
+func (r *batchRun) research(ctx context.Context, i int) {
+	query, client := r.queries[i], r.clients[i]
+
+	for attempt := 0; ; attempt++ {
+		if err := r.pacer.wait(ctx); err != nil {
+			return
+		}
+
+		r.update(i, pkg.BatchRunning, "", 0)
+		start := time.Now()
+		result, err := client.Research(ctx, query.Query)
+		duration := time.Since(start)
+		if ctx.Err() != nil {
+			// Interrupted: leave the query pending so the next run retries it
+			r.update(i, pkg.BatchPending, "", 0)
+			return
+		}
+
+		var statusErr *pkg.StatusError
+		if errors.As(err, &statusErr) && statusErr.Temporary() && attempt < r.retries {
+			backoff := statusErr.RetryAfter
+			if backoff <= 0 {
+				backoff = min(firstBatchBackoff<<attempt, maxBatchBackoff)
+			}
+			// Rate limits apply to every worker, so they all hold off
+			if statusErr.Status == http.StatusTooManyRequests {
+				r.pacer.pause(backoff)
+			}
+			r.update(i, pkg.BatchWaiting, fmt.Sprintf("%s, retrying in %s", statusErr, backoff.Round(time.Second)), 0)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				r.update(i, pkg.BatchPending, "", 0)
+				return
+			}
+			continue
+		}
+
+		line := batchResult{
+			Line:       query.Line,
+			Query:      query.Query,
+			Model:      client.Model(),
+			Template:   client.Template(),
+			Time:       time.Now().UTC(),
+			DurationMS: duration.Milliseconds(),
+		}
+		if err != nil {
+			line.Error = err.Error()
+		} else {
+			line.FormattedResponse = &result
+			if r.config.HistoryEnabled() {
+				saveResearch(r.config, client, "batch", query.Query, result)
+			}
+		}
+		if writeErr := r.write(line); writeErr != nil && err == nil {
+			err = fmt.Errorf("writing result: %w", writeErr)
+		}
+
+		if err != nil {
+			r.errs[i] = err
+			r.update(i, pkg.BatchFailed, err.Error(), duration)
+		} else {
+			r.update(i, pkg.BatchDone, "", duration)
+		}
+		return
+	}
+}
+
+// write appends one result line to the output file
+func (r *batchRun) write(result batchResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	r.outMu.Lock()
+	defer r.outMu.Unlock()
+	_, err = r.out.Write(append(data, '\n'))
+	return err
+}
+
+// update records a status change and reports it to the progress display
+func (r *batchRun) update(i int, status int, detail string, duration time.Duration) {
+	r.mu.Lock()
+	r.status[i] = status
+	r.mu.Unlock()
+	if r.notify != nil {
+		r.notify(pkg.BatchItemMsg{Index: i, Status: status, Detail: detail, Duration: duration})
+	}
+}
+
+// printProgress reports finished and rate limited items on stderr when there is no terminal
+func (r *batchRun) printProgress(msg tea.Msg) {
+	item, ok := msg.(pkg.BatchItemMsg)
+	if !ok {
+		return
+	}
+	query := r.queries[item.Index].Query
+	prefix := fmt.Sprintf("[%d/%d] ", item.Index+1, len(r.queries))
+	switch item.Status {
+	case pkg.BatchDone:
+		fmt.Fprintln(os.Stderr, prefix+pkg.Green("done ")+query+pkg.Muted(fmt.Sprintf(" (%dms)", item.Duration.Milliseconds())))
+	case pkg.BatchFailed:
+		fmt.Fprintln(os.Stderr, prefix+pkg.Red("failed ")+query+": "+item.Detail)
+	case pkg.BatchWaiting:
+		fmt.Fprintln(os.Stderr, prefix+pkg.YellowBold("waiting ")+query+": "+item.Detail)
+	}
+}
+
+// tally counts the outcome of every query
+func (r *batchRun) tally() (done, failed, skipped, pending int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, status := range r.status {
+		switch status {
+		case pkg.BatchDone:
+			done++
+		case pkg.BatchFailed:
+			failed++
+		case pkg.BatchSkipped:
+			skipped++
+		default:
+			pending++
+		}
+	}
+	return done, failed, skipped, pending
+}
+
+// pacer spaces out request starts across workers and holds everyone off after a rate limit
+type pacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newPacer creates a pacer allowing perMinute request starts per minute, or any number for 0
+func newPacer(perMinute int) *pacer {
+	p := &pacer{}
+	if perMinute > 0 {
+		p.interval = time.Minute / time.Duration(perMinute)
+	}
+	return p
+}
+
+// wait blocks until the caller may start a request
+func (p *pacer) wait(ctx context.Context) error {
+	p.mu.Lock()
+	start := time.Now()
+	if p.next.After(start) {
+		start = p.next
+	}
+	p.next = start.Add(p.interval)
+	p.mu.Unlock()
+
+	select {
+	case <-time.After(time.Until(start)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pause holds off every request start for at least d
+func (p *pacer) pause(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until := time.Now().Add(d); until.After(p.next) {
+		p.next = until
+	}
+}
+
+func init() {
+	batchCmd.Flags().IntVarP(&batchConcurrency, "concurrency", "c", 4, "Number of queries researched at once")
+	batchCmd.Flags().StringVar(&batchOut, "out", "", "JSON Lines file for results (default <input>.results.jsonl)")
+	batchCmd.Flags().IntVar(&batchRate, "rate", 0, "Maximum requests started per minute, 0 for no limit")
+	batchCmd.Flags().IntVar(&batchRetries, "retries", 3, "Retries for rate limited or temporarily failing queries")
+	batchCmd.Flags().BoolVar(&batchRestart, "restart", false, "Ignore results from a previous run and start over")
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+func TestReadBatchCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []batchQuery
+	}{
+		{
+			name:  "header with every column",
+			input: "query,model,template\nWhat is Go?,gpt-4o,brief\nWhat is Rust?,,\n",
+			want: []batchQuery{
+				{Line: 2, Query: "What is Go?", Model: "gpt-4o", Template: "brief"},
+				{Line: 3, Query: "What is Rust?"},
+			},
+		},
+		{
+			name:  "columns in any order and case",
+			input: "Template, Query\nbrief,What is Go?\n",
+			want:  []batchQuery{{Line: 2, Query: "What is Go?", Template: "brief"}},
+		},
+		{
+			name:  "no header uses the first column",
+			input: "What is Go?,ignored\nWhat is Rust?\n",
+			want: []batchQuery{
+				{Line: 1, Query: "What is Go?"},
+				{Line: 2, Query: "What is Rust?"},
+			},
+		},
+		{
+			name:  "quoted fields keep commas and quotes",
+			input: "query\n\"Go, Rust or Zig?\"\n\"What does \"\"go\"\" mean?\"\n",
+			want: []batchQuery{
+				{Line: 2, Query: "Go, Rust or Zig?"},
+				{Line: 3, Query: `What does "go" mean?`},
+			},
+		},
+		{
+			name:  "comments and empty queries are skipped",
+			input: "query,model\n# a comment\n,gpt-4o\n  What is Go?  ,\n",
+			want:  []batchQuery{{Line: 4, Query: "What is Go?"}},
+		},
+		{
+			name:  "quoted line breaks keep later line numbers",
+			input: "query\n\"What is\nGo?\"\nWhat is Rust?\n",
+			want: []batchQuery{
+				{Line: 2, Query: "What is\nGo?"},
+				{Line: 4, Query: "What is Rust?"},
+			},
+		},
+		{
+			name:  "empty file",
+			input: "",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readBatchCSV(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readBatchCSV() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := readBatchCSV(strings.NewReader("query\n\"unterminated\n")); err == nil {
+		t.Error("readBatchCSV() of a broken quote returned no error")
+	}
+}
+
+func TestReadBatchFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		input string
+		want  []batchQuery
+	}{
+		{
+			name:  "text lines",
+			file:  "queries.txt",
+			input: "# research list\nWhat is Go?\n\n  What is Rust?  \r\n",
+			want: []batchQuery{
+				{Line: 2, Query: "What is Go?"},
+				{Line: 4, Query: "What is Rust?"},
+			},
+		},
+		{
+			name:  "CSV by extension",
+			file:  "queries.CSV",
+			input: "query,template\nWhat is Go?,brief\n",
+			want:  []batchQuery{{Line: 2, Query: "What is Go?", Template: "brief"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			writeTestFile(t, path, tt.input)
+			got, err := readBatchFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readBatchFile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchPrepareResume(t *testing.T) {
+	client, err := pkg.NewClient(pkg.WithAPIKey("sk-test"), pkg.WithBaseURL("http://127.0.0.1:0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := pkg.GetModelOrder()[0]
+	if other == client.Model() {
+		other = pkg.GetModelOrder()[1]
+	}
+
+	// A previous run answered "done", failed "failed" and was cut off while writing
+	answered := batchResult{Query: "done", Model: client.Model(), Template: client.Template()}
+	failed := batchResult{Query: "failed", Model: client.Model(), Template: client.Template(), Error: "boom"}
+	var previous []string
+	for _, result := range []batchResult{answered, failed} {
+		line, _ := json.Marshal(result)
+		previous = append(previous, string(line))
+	}
+	previous = append(previous, `{"query": "cut`)
+
+	queries := []batchQuery{
+		{Query: "done"},
+		{Query: "failed"},
+		{Query: "done", Model: other},
+		{Query: "new"},
+		{Query: "done", Model: "no-such-model"},
+	}
+
+	tests := []struct {
+		name    string
+		restart bool
+		want    []int
+	}{
+		{"resume", false, []int{pkg.BatchSkipped, pkg.BatchPending, pkg.BatchPending, pkg.BatchPending, pkg.BatchFailed}},
+		{"restart", true, []int{pkg.BatchPending, pkg.BatchPending, pkg.BatchPending, pkg.BatchPending, pkg.BatchFailed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "out.jsonl")
+			writeTestFile(t, outPath, strings.Join(previous, "\n")+"\n")
+
+			run := &batchRun{queries: queries}
+			if err := run.prepare(client, outPath, tt.restart); err != nil {
+				t.Fatal(err)
+			}
+			run.out.Close()
+
+			if !reflect.DeepEqual(run.status, tt.want) {
+				t.Errorf("status = %v, want %v", run.status, tt.want)
+			}
+			if run.errs[4] == nil {
+				t.Error("query with an unknown model has no error")
+			}
+			data, _ := os.ReadFile(outPath)
+			if tt.restart != (len(data) == 0) {
+				t.Errorf("output file holds %d bytes after prepare, restart %v", len(data), tt.restart)
+			}
+		})
+	}
+}
+
+func TestPacer(t *testing.T) {
+	tests := []struct {
+		name        string
+		perMinute   int
+		pause       time.Duration
+		waits       int
+		wantAtLeast time.Duration
+	}{
+		{"unlimited", 0, 0, 5, 0},
+		{"first start is immediate", 600, 0, 1, 0},
+		{"starts are spaced", 600, 0, 3, 200 * time.Millisecond},
+		{"pause holds off starts", 0, 150 * time.Millisecond, 1, 150 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPacer(tt.perMinute)
+			p.pause(tt.pause)
+			start := time.Now()
+			for range tt.waits {
+				if err := p.wait(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			elapsed := time.Since(start)
+			if elapsed < tt.wantAtLeast || elapsed > tt.wantAtLeast+time.Second {
+				t.Errorf("waits took %v, want about %v", elapsed, tt.wantAtLeast)
+			}
+		})
+	}
+
+	t.Run("interval", func(t *testing.T) {
+		if got := newPacer(60).interval; got != time.Second {
+			t.Errorf("interval for 60 per minute = %v, want 1s", got)
+		}
+	})
+
+	t.Run("cancelled wait", func(t *testing.T) {
+		p := newPacer(0)
+		p.pause(time.Hour)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := p.wait(ctx); err == nil {
+			t.Error("wait() with a cancelled context returned no error")
+		}
+	})
+}
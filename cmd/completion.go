@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate a shell completion script",
+	Long: `Generate a completion script for commands, flags, model IDs, templates, profiles and history IDs.
+
+Load completions for the current shell:
+  bash:        source <(ptn completion bash)
+  zsh:         source <(ptn completion zsh)
+  fish:        ptn completion fish | source
+  powershell:  ptn completion powershell | Out-String | Invoke-Expression
+
+Load them in every new shell:
+  bash:  ptn completion bash > ~/.local/share/bash-completion/completions/ptn
+  zsh:   ptn completion zsh > "${fpath[1]}/_ptn"   (needs compinit in ~/.zshrc)
+  fish:  ptn completion fish > ~/.config/fish/completions/ptn.fish`,
+	Args:                  cobra.ExactArgs(1),
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	DisableFlagsInUseLine: true,
+	// Completion scripts go straight to stdout, so skip the config and logging setup
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = rootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			err = rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		default:
+			err = fmt.Errorf("unsupported shell '%s', expected bash, zsh, fish or powershell", args[0])
+		}
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+// completeModelIDs completes a model ID argument, described by the model's description
+func completeModelIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeConcreteModels(toComplete)
+}
+
+// completeModelChoices completes a model ID or auto argument
+func completeModelChoices(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeModelFlag(cmd, args, toComplete)
+}
+
+// completeModelFlag completes a model ID or auto, described by the model's description
+func completeModelFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	completions, directive := completeConcreteModels(toComplete)
+	if strings.HasPrefix(pkg.AutoModel, toComplete) {
+		completions = append(completions, cobra.CompletionWithDesc(pkg.AutoModel, "Pick a model for each query"))
+	}
+	return completions, directive
+}
+
+// completeConcreteModels completes a model ID, described by the model's description
+func completeConcreteModels(toComplete string) ([]string, cobra.ShellCompDirective) {
+	models := pkg.GetAvailableModels()
+	var completions []string
+	for _, id := range pkg.GetModelOrder() {
+		if strings.HasPrefix(id, toComplete) {
+			completions = append(completions, cobra.CompletionWithDesc(id, models[id].Description))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTemplateFlag completes a prompt template name, described by the template's description
+func completeTemplateFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	templates := pkg.GetTemplates()
+	var completions []string
+	for _, name := range pkg.GetTemplateNames() {
+		if strings.HasPrefix(name, toComplete) {
+			completions = append(completions, cobra.CompletionWithDesc(name, templates[name].Description))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfiles completes a profile name argument
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeProfileFlag(cmd, args, toComplete)
+}
+
+// completeProfileFlag completes a profile name, marking the active profile
+func completeProfileFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := config.ProfileNames()
+	if len(names) == 0 {
+		names = []string{defaultProfile}
+	}
+
+	var completions []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, toComplete) {
+			continue
+		}
+		if name == config.GetProfile() {
+			completions = append(completions, cobra.CompletionWithDesc(name, "active profile"))
+		} else {
+			completions = append(completions, name)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeHistoryIDs completes a history entry ID, newest first, described by its query
+func completeHistoryIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	entries, err := readHistory()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.ID, toComplete) {
+			query := strings.Join(strings.Fields(entry.Query), " ")
+			completions = append(completions, cobra.CompletionWithDesc(entry.ID, entry.Time.Local().Format("2006-01-02 15:04")+" "+query))
+		}
+	}
+	// Keep the newest-first order instead of letting the shell sort IDs alphabetically
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// completeConfigKeys completes a config key argument, described by what the key does
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var completions []string
+	for _, field := range configFields {
+		if strings.HasPrefix(field.Key, toComplete) {
+			completions = append(completions, cobra.CompletionWithDesc(field.Key, field.Description))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigSet completes the key of 'config set', then a value suited to the key's type
+func completeConfigSet(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeConfigKeys(cmd, args, toComplete)
+	}
+	if len(args) > 1 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	field, err := findConfigField(args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	switch field.Key {
+	case "output":
+		return completeOutputFlag(cmd, nil, toComplete)
+	case "lang":
+		return completeLangFlag(cmd, nil, toComplete)
+	}
+	switch field.Type {
+	case "model":
+		if field.Key == "current_model" {
+			return completeModelFlag(cmd, nil, toComplete)
+		}
+		return completeConcreteModels(toComplete)
+	case "template":
+		return completeTemplateFlag(cmd, nil, toComplete)
+	case "theme":
+		return completeThemeFlag(cmd, nil, toComplete)
+	case "bool":
+		return []string{"true", "false"}, cobra.ShellCompDirectiveNoFileComp
+	case "path":
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeThemeFlag completes a built-in or custom theme name
+func completeThemeFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var custom map[string]pkg.Theme
+	if config, err := LoadConfig(); err == nil {
+		custom = config.Themes
+	}
+	return filterPrefix(pkg.ThemeNames(custom), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOutputFlag completes an output format
+func completeOutputFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return filterPrefix(pkg.GetOutputFormats(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeLangFlag completes a language code, described by the language's name
+func completeLangFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var completions []string
+	for _, code := range append(pkg.GetLanguages(), langAuto) {
+		if !strings.HasPrefix(code, toComplete) {
+			continue
+		}
+		if locale, err := pkg.GetLocale(code); err == nil {
+			completions = append(completions, cobra.CompletionWithDesc(code, locale.Name))
+		} else {
+			completions = append(completions, cobra.CompletionWithDesc(code, "follow LANG"))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// filterPrefix returns the values that start with prefix
+func filterPrefix(values []string, prefix string) []string {
+	var matches []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			matches = append(matches, value)
+		}
+	}
+	return matches
+}
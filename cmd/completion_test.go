@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// writeTestHome creates a home directory with a config file and history for completion
+func writeTestHome(t *testing.T, config string, history ...historyEntry) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PHOTON_PROFILE", "")
+
+	dir := filepath.Join(home, ".photon")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if config != "" {
+		if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var lines bytes.Buffer
+	for _, entry := range history {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines.Write(append(data, '\n'))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "history.jsonl"), lines.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// complete runs the hidden __complete command and returns the completions, without
+// descriptions, their descriptions and the directive
+func complete(t *testing.T, args ...string) ([]string, map[string]string, cobra.ShellCompDirective) {
+	t.Helper()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(io.Discard)
+	rootCmd.SetArgs(append([]string{cobra.ShellCompRequestCmd}, args...))
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+		profileOverride = ""
+	})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+	descriptions := make(map[string]string)
+	var directive cobra.ShellCompDirective
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if code, ok := strings.CutPrefix(line, ":"); ok {
+			n, err := strconv.Atoi(code)
+			if err != nil {
+				t.Fatalf("bad directive line %q", line)
+			}
+			directive = cobra.ShellCompDirective(n)
+			continue
+		}
+		value, description, _ := strings.Cut(line, "\t")
+		values = append(values, value)
+		descriptions[value] = description
+	}
+	return values, descriptions, directive
+}
+
+func TestCompleteModelSet(t *testing.T) {
+	writeTestHome(t, "")
+	values, descriptions, directive := complete(t, "model", "set", "")
+
+	want := append(pkg.GetModelOrder(), pkg.AutoModel)
+	if strings.Join(values, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", values, want)
+	}
+	models := pkg.GetAvailableModels()
+	for _, id := range pkg.GetModelOrder() {
+		if descriptions[id] != models[id].Description {
+			t.Errorf("%s: got description %q, want %q", id, descriptions[id], models[id].Description)
+		}
+	}
+	if descriptions[pkg.AutoModel] == "" {
+		t.Error("auto has no description")
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("got directive %d, want %d", directive, cobra.ShellCompDirectiveNoFileComp)
+	}
+
+	// A prefix narrows the list, and a second argument completes nothing
+	if values, _, _ := complete(t, "model", "set", "deepseek-r"); strings.Join(values, ",") != "deepseek-r1" {
+		t.Errorf("prefix deepseek-r: got %v", values)
+	}
+	if values, _, _ := complete(t, "model", "set", "kimi", ""); len(values) != 0 {
+		t.Errorf("second argument: got %v, want nothing", values)
+	}
+}
+
+func TestCompleteHistoryExport(t *testing.T) {
+	now := time.Now()
+	writeTestHome(t, "",
+		historyEntry{ID: "a1b2c3d4", Time: now.Add(-time.Hour), Query: "what is\ngo"},
+		historyEntry{ID: "ffee0011", Time: now, Query: "what is rust"},
+	)
+	values, descriptions, directive := complete(t, "history", "export", "")
+
+	// Newest first, kept in that order
+	if strings.Join(values, ",") != "ffee0011,a1b2c3d4" {
+		t.Errorf("got %v, want the newest entry first", values)
+	}
+	if want := now.Add(-time.Hour).Local().Format("2006-01-02 15:04") + " what is go"; descriptions["a1b2c3d4"] != want {
+		t.Errorf("got description %q, want %q", descriptions["a1b2c3d4"], want)
+	}
+	if want := cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder; directive != want {
+		t.Errorf("got directive %d, want %d", directive, want)
+	}
+
+	if values, _, _ := complete(t, "history", "export", "a1"); strings.Join(values, ",") != "a1b2c3d4" {
+		t.Errorf("prefix a1: got %v", values)
+	}
+}
+
+func TestCompleteProfileFlag(t *testing.T) {
+	writeTestHome(t, `{"version": 3, "profile": "work", "profiles": {"default": {}, "work": {}, "home": {}}}`)
+	values, descriptions, directive := complete(t, "--profile", "")
+
+	if strings.Join(values, ",") != "default,home,work" {
+		t.Errorf("got %v, want every profile", values)
+	}
+	if descriptions["work"] != "active profile" || descriptions["home"] != "" {
+		t.Errorf("got descriptions %v, want only work marked active", descriptions)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("got directive %d, want %d", directive, cobra.ShellCompDirectiveNoFileComp)
+	}
+}
+
+func TestCompleteTemplateFlag(t *testing.T) {
+	writeTestHome(t, "")
+	values, descriptions, directive := complete(t, "--template", "")
+
+	if strings.Join(values, ",") != strings.Join(pkg.GetTemplateNames(), ",") {
+		t.Errorf("got %v, want %v", values, pkg.GetTemplateNames())
+	}
+	templates := pkg.GetTemplates()
+	for _, name := range pkg.GetTemplateNames() {
+		if descriptions[name] != templates[name].Description {
+			t.Errorf("%s: got description %q, want %q", name, descriptions[name], templates[name].Description)
+		}
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("got directive %d, want %d", directive, cobra.ShellCompDirectiveNoFileComp)
+	}
+}
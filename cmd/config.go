@@ -1,30 +1,530 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Jacky040124/photon/pkg"
 )
 
+// Config holds the settings of one profile. LoadConfig returns the effective
+// Config after merging every layer for the active profile.
 type Config struct {
-	OpenRouterKey string `json:"openrouter_key,omitempty"`
-	CurrentModel  string `json:"current_model"`
+	OpenRouterKey  string               `json:"openrouter_key,omitempty"`
+	CurrentModel   string               `json:"current_model"`
+	AutoClassifier string               `json:"auto_classifier,omitempty"`
+	Template       string               `json:"template,omitempty"`
+	Theme          string               `json:"theme,omitempty"`
+	Timeout        string               `json:"timeout,omitempty"`
+	BaseURL        string               `json:"base_url,omitempty"`
+	Proxy          string               `json:"proxy,omitempty"`
+	CAFile         string               `json:"ca_file,omitempty"`
+	ClientCert     string               `json:"client_cert,omitempty"`
+	ClientKey      string               `json:"client_key,omitempty"`
+	ConnectTimeout string               `json:"connect_timeout,omitempty"`
+	ReadTimeout    string               `json:"read_timeout,omitempty"`
+	Output         string               `json:"output,omitempty"`
+	Lang           string               `json:"lang,omitempty"`
+	Emoji          *bool                `json:"emoji,omitempty"`
+	History        *bool                `json:"history,omitempty"`
+	NotesDir       string               `json:"notes_dir,omitempty"`
+	NoteFilename   string               `json:"note_filename,omitempty"`
+	DailyNote      string               `json:"daily_note,omitempty"`
+	Redact         *bool                `json:"redact,omitempty"`
+	Themes         map[string]pkg.Theme `json:"themes,omitempty"`
+	RedactRules    []pkg.RedactRule     `json:"redact_rules,omitempty"`
+	RouteRules     []pkg.RouteRule      `json:"route_rules,omitempty"`
+
+	// profile is the name of the active profile
+	profile string
+	// user is the user-level config file, which is what Save writes
+	user *userConfig
+	// origins records which layer each effective value came from
+	origins map[string]string
+	// projectPath is the project config file that was merged, if any
+	projectPath string
+	// credentialsErr records why a stored API key was refused or could not be read
+	credentialsErr error
+	// warnings lists problems in config files that did not stop loading, such as unknown keys
+	warnings []string
+}
+
+// userConfig is the layout of the user config file: named profiles and the active one
+type userConfig struct {
+	Version  int                `json:"version"`
+	Profile  string             `json:"profile,omitempty"`
+	Profiles map[string]*Config `json:"profiles"`
+}
+
+// defaultProfile is the profile used when none is selected
+const defaultProfile = "default"
+
+// profileNamePattern restricts profile names to characters safe in file names and keyring accounts
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// profileOverride is the profile selected with --profile; it wins over PHOTON_PROFILE and the config file
+var profileOverride string
+
+// Configuration layer names, from lowest to highest precedence
+const (
+	originDefault     = "default"
+	originUser        = "user"
+	originKeyring     = "keyring"
+	originCredentials = "credentials"
+	originProject     = "project"
+	originEnv         = "env"
+	originFlag        = "flag"
+)
+
+// projectConfigNames are the per-project config files searched for, in order of preference
+var projectConfigNames = []string{".photon.json", ".photon.toml"}
+
+// configField describes a scalar configuration key, its type and how it can be overridden
+type configField struct {
+	Key         string
+	Type        string
+	Description string
+	Env         string
+	Flag        string
+	Secret      bool
+	get         func(*Config) string
+	set         func(*Config, string)
+	validate    func(*Config, string) error
+}
+
+// configFields lists the scalar configuration keys in display order
+var configFields = []configField{
+	{
+		Key:         "current_model",
+		Type:        "model",
+		Description: "Model used for research queries, or auto to pick one for each query",
+		Env:         "PHOTON_MODEL",
+		Flag:        "model",
+		get:         func(c *Config) string { return c.CurrentModel },
+		set:         func(c *Config, v string) { c.CurrentModel = v },
+		validate:    validateModelChoiceValue,
+	},
+	{
+		Key:         "auto_classifier",
+		Type:        "model",
+		Description: "Model the auto model asks for each query's category; unset uses local heuristics",
+		Env:         "PHOTON_AUTO_CLASSIFIER",
+		get:         func(c *Config) string { return c.AutoClassifier },
+		set:         func(c *Config, v string) { c.AutoClassifier = v },
+		validate:    validateModelValue,
+	},
+	{
+		Key:         "template",
+		Type:        "template",
+		Description: "Prompt template for research queries",
+		Env:         "PHOTON_TEMPLATE",
+		Flag:        "template",
+		get:         func(c *Config) string { return c.Template },
+		set:         func(c *Config, v string) { c.Template = v },
+		validate:    validateTemplateValue,
+	},
+	{
+		Key:         "theme",
+		Type:        "theme",
+		Description: "Color theme, built-in or defined under themes",
+		Env:         "PHOTON_THEME",
+		Flag:        "theme",
+		get:         func(c *Config) string { return c.Theme },
+		set:         func(c *Config, v string) { c.Theme = v },
+		validate:    validateThemeValue,
+	},
+	{
+		Key:         "timeout",
+		Type:        "duration",
+		Description: "Maximum time to wait for an answer, e.g. 30s or 2m, or 0 for no limit",
+		Env:         "PHOTON_TIMEOUT",
+		Flag:        "timeout",
+		get:         func(c *Config) string { return c.Timeout },
+		set:         func(c *Config, v string) { c.Timeout = v },
+		validate:    validateTimeoutValue,
+	},
+	{
+		Key:         "base_url",
+		Type:        "url",
+		Description: "OpenRouter-compatible API base URL",
+		Env:         "PHOTON_BASE_URL",
+		get:         func(c *Config) string { return c.BaseURL },
+		set:         func(c *Config, v string) { c.BaseURL = v },
+		validate:    validateURLValue,
+	},
+	{
+		Key:         "proxy",
+		Type:        "url",
+		Description: "Proxy for API requests; defaults to HTTPS_PROXY and NO_PROXY",
+		Env:         "PHOTON_PROXY",
+		get:         func(c *Config) string { return c.Proxy },
+		set:         func(c *Config, v string) { c.Proxy = v },
+		validate:    validateProxyValue,
+	},
+	{
+		Key:         "ca_file",
+		Type:        "path",
+		Description: "PEM CA bundle to trust in addition to the system roots",
+		Env:         "PHOTON_CA_FILE",
+		get:         func(c *Config) string { return c.CAFile },
+		set:         func(c *Config, v string) { c.CAFile = v },
+		validate:    validateFileValue,
+	},
+	{
+		Key:         "client_cert",
+		Type:        "path",
+		Description: "PEM client certificate for mutual TLS",
+		Env:         "PHOTON_CLIENT_CERT",
+		get:         func(c *Config) string { return c.ClientCert },
+		set:         func(c *Config, v string) { c.ClientCert = v },
+		validate:    validateFileValue,
+	},
+	{
+		Key:         "client_key",
+		Type:        "path",
+		Description: "PEM private key for the client certificate",
+		Env:         "PHOTON_CLIENT_KEY",
+		get:         func(c *Config) string { return c.ClientKey },
+		set:         func(c *Config, v string) { c.ClientKey = v },
+		validate:    validateFileValue,
+	},
+	{
+		Key:         "connect_timeout",
+		Type:        "duration",
+		Description: "Maximum time to connect to the API, including the TLS handshake",
+		Env:         "PHOTON_CONNECT_TIMEOUT",
+		get:         func(c *Config) string { return c.ConnectTimeout },
+		set:         func(c *Config, v string) { c.ConnectTimeout = v },
+		validate:    validateDurationValue,
+	},
+	{
+		Key:         "read_timeout",
+		Type:        "duration",
+		Description: "Maximum time to wait for the API to start responding",
+		Env:         "PHOTON_READ_TIMEOUT",
+		get:         func(c *Config) string { return c.ReadTimeout },
+		set:         func(c *Config, v string) { c.ReadTimeout = v },
+		validate:    validateDurationValue,
+	},
+	{
+		Key:         "output",
+		Type:        "enum",
+		Description: "Default output format: " + strings.Join(pkg.GetOutputFormats(), ", "),
+		Env:         "PHOTON_OUTPUT",
+		Flag:        "output",
+		get:         func(c *Config) string { return c.Output },
+		set:         func(c *Config, v string) { c.Output = v },
+		validate:    validateOutputValue,
+	},
+	{
+		Key:         "lang",
+		Type:        "enum",
+		Description: "Language of answers and messages: " + strings.Join(pkg.GetLanguages(), ", ") + ", or auto to follow LANG",
+		Env:         "PHOTON_LANG",
+		Flag:        "lang",
+		get:         func(c *Config) string { return c.Lang },
+		set:         func(c *Config, v string) { c.Lang = v },
+		validate:    validateLangValue,
+	},
+	{
+		Key:         "emoji",
+		Type:        "bool",
+		Description: "Show emoji in output",
+		Env:         "PHOTON_EMOJI",
+		get: func(c *Config) string {
+			if c.Emoji == nil {
+				return ""
+			}
+			return strconv.FormatBool(*c.Emoji)
+		},
+		set: func(c *Config, v string) {
+			if v == "" {
+				c.Emoji = nil
+				return
+			}
+			enabled, _ := strconv.ParseBool(v)
+			c.Emoji = &enabled
+		},
+		validate: validateBoolValue,
+	},
+	{
+		Key:         "history",
+		Type:        "bool",
+		Description: "Record research results in the history file (off by default)",
+		Env:         "PHOTON_HISTORY",
+		get: func(c *Config) string {
+			if c.History == nil {
+				return ""
+			}
+			return strconv.FormatBool(*c.History)
+		},
+		set: func(c *Config, v string) {
+			if v == "" {
+				c.History = nil
+				return
+			}
+			enabled, _ := strconv.ParseBool(v)
+			c.History = &enabled
+		},
+		validate: validateBoolValue,
+	},
+	{
+		Key:         "redact",
+		Type:        "bool",
+		Description: "Replace secrets and personal data in queries with placeholders before sending",
+		Env:         "PHOTON_REDACT",
+		get: func(c *Config) string {
+			if c.Redact == nil {
+				return ""
+			}
+			return strconv.FormatBool(*c.Redact)
+		},
+		set: func(c *Config, v string) {
+			if v == "" {
+				c.Redact = nil
+				return
+			}
+			enabled, _ := strconv.ParseBool(v)
+			c.Redact = &enabled
+		},
+		validate: validateBoolValue,
+	},
+	{
+		Key:         "notes_dir",
+		Type:        "path",
+		Description: "Directory saved notes go to, such as an Obsidian vault",
+		Env:         "PHOTON_NOTES_DIR",
+		get:         func(c *Config) string { return c.NotesDir },
+		set:         func(c *Config, v string) { c.NotesDir = v },
+		validate:    validateDirValue,
+	},
+	{
+		Key:         "note_filename",
+		Type:        "pattern",
+		Description: "File name pattern for saved notes; the extension is added",
+		Env:         "PHOTON_NOTE_FILENAME",
+		get:         func(c *Config) string { return c.NoteFilename },
+		set:         func(c *Config, v string) { c.NoteFilename = v },
+		validate:    validateNoteFilenameValue,
+	},
+	{
+		Key:         "daily_note",
+		Type:        "pattern",
+		Description: "File name pattern of the daily note --append adds to",
+		Env:         "PHOTON_DAILY_NOTE",
+		get:         func(c *Config) string { return c.DailyNote },
+		set:         func(c *Config, v string) { c.DailyNote = v },
+		validate:    validateNoteFilenameValue,
+	},
+	{
+		Key:         "openrouter_key",
+		Type:        "secret",
+		Description: "OpenRouter API key",
+		Env:         "PHOTON_OPEN_ROUTER_KEY",
+		Secret:      true,
+		get:         func(c *Config) string { return c.OpenRouterKey },
+		set:         func(c *Config, v string) { c.OpenRouterKey = v },
+		validate:    validateSecretValue,
+	},
+}
+
+// findConfigField looks up a scalar configuration key
+func findConfigField(key string) (configField, error) {
+	for _, field := range configFields {
+		if field.Key == key {
+			return field, nil
+		}
+	}
+	return configField{}, fmt.Errorf("unknown config key '%s'", key)
+}
+
+// validateModelValue checks that a value is a known model ID
+func validateModelValue(c *Config, v string) error {
+	if !pkg.ValidateModel(v) {
+		return fmt.Errorf("invalid model '%s', expected one of: %s", v, strings.Join(pkg.GetModelOrder(), ", "))
+	}
+	return nil
+}
+
+// validateModelChoiceValue checks that a value is a known model ID or auto
+func validateModelChoiceValue(c *Config, v string) error {
+	if v != pkg.AutoModel && !pkg.ValidateModel(v) {
+		return fmt.Errorf("invalid model '%s', expected auto or one of: %s", v, strings.Join(pkg.GetModelOrder(), ", "))
+	}
+	return nil
+}
+
+// validateTemplateValue checks that a value is a known prompt template
+func validateTemplateValue(c *Config, v string) error {
+	if !pkg.ValidateTemplate(v) {
+		return fmt.Errorf("invalid template '%s', expected one of: %s", v, strings.Join(pkg.GetTemplateNames(), ", "))
+	}
+	return nil
+}
+
+// validateThemeValue checks that a value is a built-in or custom theme
+func validateThemeValue(c *Config, v string) error {
+	_, err := pkg.ResolveTheme(v, c.Themes)
+	return err
+}
+
+// validateDurationValue checks that a value is a positive Go duration
+func validateDurationValue(c *Config, v string) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("invalid duration '%s', expected a value like 30s or 2m", v)
+	}
+	if d <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	return nil
+}
+
+// validateTimeoutValue checks that a value is a positive Go duration, or 0 to disable the timeout
+func validateTimeoutValue(c *Config, v string) error {
+	if d, err := time.ParseDuration(v); err == nil && d == 0 {
+		return nil
+	}
+	return validateDurationValue(c, v)
+}
+
+// validateURLValue checks that a value is an absolute http or https URL
+func validateURLValue(c *Config, v string) error {
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL '%s', expected an http or https URL", v)
+	}
+	return nil
+}
+
+// validateProxyValue checks that a value is an http, https or socks5 proxy URL
+func validateProxyValue(c *Config, v string) error {
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") || u.Host == "" {
+		return fmt.Errorf("invalid proxy '%s', expected an http, https or socks5 URL", v)
+	}
+	return nil
+}
+
+// validateFileValue checks that a value names a readable file
+func validateFileValue(c *Config, v string) error {
+	info, err := os.Stat(expandHome(v))
+	if err != nil {
+		return fmt.Errorf("cannot read '%s': %w", v, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("'%s' is a directory, expected a file", v)
+	}
+	return nil
+}
+
+// validateDirValue checks that a value is a directory, or a path where one can be created
+func validateDirValue(c *Config, v string) error {
+	info, err := os.Stat(expandHome(v))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read '%s': %w", v, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", v)
+	}
+	return nil
+}
+
+// validateNoteFilenameValue checks that a value is a usable note filename pattern
+func validateNoteFilenameValue(c *Config, v string) error {
+	return pkg.ValidateNoteFilename(v)
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// validateOutputValue checks that a value is a supported output format
+func validateOutputValue(c *Config, v string) error {
+	if !pkg.ValidateOutputFormat(v) {
+		return fmt.Errorf("invalid output format '%s', expected one of: %s", v, strings.Join(pkg.GetOutputFormats(), ", "))
+	}
+	return nil
 }
 
-// Validate checks if required configuration is present
+// validateLangValue checks that a value is a supported language or auto
+func validateLangValue(c *Config, v string) error {
+	if v == langAuto {
+		return nil
+	}
+	_, err := pkg.GetLocale(v)
+	return err
+}
+
+// validateBoolValue checks that a value parses as a boolean
+func validateBoolValue(c *Config, v string) error {
+	if _, err := strconv.ParseBool(v); err != nil {
+		return fmt.Errorf("invalid boolean '%s', expected true or false", v)
+	}
+	return nil
+}
+
+// validateSecretValue checks that an API key has no stray whitespace
+func validateSecretValue(c *Config, v string) error {
+	if strings.TrimSpace(v) != v || strings.ContainsAny(v, " \t\n") {
+		return fmt.Errorf("API key must not contain whitespace")
+	}
+	return nil
+}
+
+// flagOverrides holds config values passed as CLI flags; they win over every other layer
+var flagOverrides = map[string]string{}
+
+// Validate checks if required configuration is present and every set value is valid
 func (c *Config) Validate() error {
-	if c.OpenRouterKey == "" && os.Getenv("PHOTON_OPEN_ROUTER_KEY") == "" {
-		return fmt.Errorf("PHOTON_OPEN_ROUTER_KEY environment variable is required")
+	if c.OpenRouterKey == "" {
+		if c.credentialsErr != nil {
+			return c.credentialsErr
+		}
+		return fmt.Errorf("no OpenRouter API key configured")
+	}
+
+	return c.ValidateSettings()
+}
+
+// ValidateSettings checks that every set value is valid, without requiring an API key
+func (c *Config) ValidateSettings() error {
+	for _, field := range configFields {
+		if value := field.get(c); value != "" {
+			if err := field.validate(c, value); err != nil {
+				return fmt.Errorf("%s: %w%s", field.Key, err, c.describeOrigin(field.Key))
+			}
+		}
+	}
+
+	// Validate theme even when unset, so broken custom themes are reported
+	if _, err := c.GetTheme(); err != nil {
+		return err
+	}
+	if _, err := c.NewRedactor(); err != nil {
+		return fmt.Errorf("redact_rules: %w%s", err, c.describeOrigin("redact_rules"))
 	}
-	
-	// Validate model if set
-	if c.CurrentModel != "" && !pkg.ValidateModel(c.CurrentModel) {
-		return fmt.Errorf("invalid model '%s'", c.CurrentModel)
+	if _, err := pkg.NewRouter(c.AutoClassifier, c.RouteRules...); err != nil {
+		return fmt.Errorf("route_rules: %w%s", err, c.describeOrigin("route_rules"))
 	}
-	
+
 	return nil
 }
 
@@ -44,14 +544,362 @@ func (c *Config) GetCurrentModel() string {
 	return c.CurrentModel
 }
 
+// GetTemplate returns the prompt template name, defaulting if not set
+func (c *Config) GetTemplate() string {
+	if c.Template == "" {
+		return pkg.GetDefaultTemplate()
+	}
+	return c.Template
+}
+
+// GetTimeout returns the request timeout, defaulting if not set or invalid. Zero means no timeout.
+func (c *Config) GetTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.Timeout); err == nil && d >= 0 {
+		return d
+	}
+	return pkg.DefaultTimeout
+}
+
+// GetOutput returns the output format, defaulting if not set
+func (c *Config) GetOutput() string {
+	if c.Output == "" {
+		return pkg.DefaultOutputFormat
+	}
+	return c.Output
+}
+
+// langAuto picks the language from the LC_ALL, LC_MESSAGES or LANG environment variables
+const langAuto = "auto"
+
+// GetLanguage returns the language code answers and messages use, defaulting to English
+func (c *Config) GetLanguage() string {
+	if c.Lang != langAuto {
+		if code, ok := pkg.NormalizeLanguage(c.Lang); ok {
+			return code
+		}
+		return pkg.DefaultLanguage
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(env); value != "" {
+			if code, ok := pkg.NormalizeLanguage(value); ok {
+				return code
+			}
+			break
+		}
+	}
+	return pkg.DefaultLanguage
+}
+
+// EmojiEnabled reports whether emoji should be shown
+func (c *Config) EmojiEnabled() bool {
+	return c.Emoji == nil || *c.Emoji
+}
+
+// RedactionEnabled reports whether queries should be redacted before they are sent
+func (c *Config) RedactionEnabled() bool {
+	return !noRedact && (c.Redact == nil || *c.Redact)
+}
+
+// NewRedactor returns a redactor with the built-in and configured rules, or nil when redaction is off
+func (c *Config) NewRedactor() (*pkg.Redactor, error) {
+	if !c.RedactionEnabled() {
+		return nil, nil
+	}
+	return pkg.NewRedactor(c.RedactRules...)
+}
+
+// HistoryEnabled reports whether research results should be recorded in the history file.
+// Recording is opt-in, since queries may hold things the user would not want kept on disk.
+func (c *Config) HistoryEnabled() bool {
+	return c.History != nil && *c.History
+}
+
+// GetNotesDir returns the directory notes are written to, defaulting to the current directory
+func (c *Config) GetNotesDir() string {
+	if c.NotesDir == "" {
+		return "."
+	}
+	return expandHome(c.NotesDir)
+}
+
+// GetNoteFilename returns the note filename pattern, defaulting if not set
+func (c *Config) GetNoteFilename() string {
+	if c.NoteFilename == "" {
+		return pkg.DefaultNoteFilename
+	}
+	return c.NoteFilename
+}
+
+// GetDailyNote returns the daily note filename pattern, defaulting if not set
+func (c *Config) GetDailyNote() string {
+	if c.DailyNote == "" {
+		return pkg.DefaultDailyNote
+	}
+	return c.DailyNote
+}
+
+// NewClient returns an API client for the configured key, model, template and connection settings
+func (c *Config) NewClient() (*pkg.Client, error) {
+	httpClient, err := pkg.NewHTTPClient(c.HTTPConfig())
+	if err != nil {
+		return nil, err
+	}
+	options, err := c.promptOptions()
+	if err != nil {
+		return nil, err
+	}
+	return pkg.NewClient(append(options,
+		pkg.WithAPIKey(c.GetOpenRouterKey()),
+		pkg.WithBaseURL(c.BaseURL),
+		pkg.WithTimeout(c.GetTimeout()),
+		pkg.WithHTTPClient(httpClient),
+		pkg.WithLogger(logger),
+	)...)
+}
+
+// NewPreviewClient creates a client that only redacts queries and builds prompts, so it
+// works without an API key and never connects anywhere
+func (c *Config) NewPreviewClient() (*pkg.Client, error) {
+	options, err := c.promptOptions()
+	if err != nil {
+		return nil, err
+	}
+	return pkg.NewPreviewClient(options...)
+}
+
+// promptOptions returns the client options that decide what a query is sent as
+func (c *Config) promptOptions() ([]pkg.Option, error) {
+	redactor, err := c.NewRedactor()
+	if err != nil {
+		return nil, err
+	}
+	router, err := pkg.NewRouter(c.AutoClassifier, c.RouteRules...)
+	if err != nil {
+		return nil, err
+	}
+	return []pkg.Option{
+		pkg.WithModel(c.GetCurrentModel()),
+		pkg.WithTemplate(c.GetTemplate()),
+		pkg.WithLanguage(c.GetLanguage()),
+		pkg.WithRedactor(redactor),
+		pkg.WithRouter(router),
+	}, nil
+}
+
+// HTTPConfig returns the connection settings for API requests
+func (c *Config) HTTPConfig() pkg.HTTPConfig {
+	config := pkg.HTTPConfig{
+		Proxy:    c.Proxy,
+		CAFile:   expandHome(c.CAFile),
+		CertFile: expandHome(c.ClientCert),
+		KeyFile:  expandHome(c.ClientKey),
+	}
+	// Invalid durations are reported by Validate; the defaults apply meanwhile
+	config.ConnectTimeout, _ = time.ParseDuration(c.ConnectTimeout)
+	config.ReadTimeout, _ = time.ParseDuration(c.ReadTimeout)
+	return config
+}
+
+// GetTheme resolves the configured theme, including custom themes
+func (c *Config) GetTheme() (pkg.Theme, error) {
+	return pkg.ResolveTheme(c.Theme, c.Themes)
+}
+
+// Origin returns the layer an effective value came from, such as "user" or "env"
+func (c *Config) Origin(key string) string {
+	if origin, ok := c.origins[key]; ok {
+		return origin
+	}
+	return originDefault
+}
+
+// describeOrigin returns a " (from ...)" suffix for error messages about a key
+func (c *Config) describeOrigin(key string) string {
+	switch c.Origin(key) {
+	case originProject:
+		return fmt.Sprintf(" (from %s)", c.projectPath)
+	case originUser:
+		return " (from user config)"
+	case originDefault:
+		return ""
+	default:
+		return fmt.Sprintf(" (from %s)", c.Origin(key))
+	}
+}
+
 // SetCurrentModel updates the current model and saves config
 func (c *Config) SetCurrentModel(modelID string) error {
-	if !pkg.ValidateModel(modelID) {
-		return fmt.Errorf("invalid model '%s'", modelID)
+	return c.SetValue("current_model", modelID)
+}
+
+// SetValue validates and stores a scalar key in the user config, then saves it.
+// Secrets go to the keyring or encrypted credentials file instead.
+func (c *Config) SetValue(key string, value string) error {
+	field, err := findConfigField(key)
+	if err != nil {
+		return err
+	}
+	if err := field.validate(c, value); err != nil {
+		return err
+	}
+
+	if field.Secret {
+		if _, err := storeAPIKey(c.profile, value, false); err != nil {
+			return err
+		}
+		field.set(c, value)
+		return c.removePlaintextKey()
+	}
+
+	field.set(c, value)
+	return c.update(func(user *userConfig) error {
+		field.set(user.profileLayer(c.profile), value)
+		return nil
+	})
+}
+
+// UnsetValue removes a scalar key from the user config, then saves it
+func (c *Config) UnsetValue(key string) error {
+	field, err := findConfigField(key)
+	if err != nil {
+		return err
+	}
+
+	if field.Secret {
+		if _, err := deleteAPIKey(c.profile); err != nil {
+			return err
+		}
+		return c.removePlaintextKey()
+	}
+
+	return c.update(func(user *userConfig) error {
+		field.set(user.profileLayer(c.profile), "")
+		return nil
+	})
+}
+
+// removePlaintextKey drops an API key stored in plain text in the user config file
+func (c *Config) removePlaintextKey() error {
+	if c.userLayer().OpenRouterKey == "" {
+		return nil
+	}
+	return c.update(func(user *userConfig) error {
+		user.profileLayer(c.profile).OpenRouterKey = ""
+		return nil
+	})
+}
+
+// profileLayer returns a profile's settings, adding an empty profile if it does not exist
+func (u *userConfig) profileLayer(name string) *Config {
+	if u.Profiles == nil {
+		u.Profiles = make(map[string]*Config)
+	}
+	if u.Profiles[name] == nil {
+		u.Profiles[name] = &Config{}
+	}
+	return u.Profiles[name]
+}
+
+// userLayer returns the active profile's settings in the user config file
+func (c *Config) userLayer() *Config {
+	if c.user == nil {
+		c.user = &userConfig{}
+	}
+	return c.user.profileLayer(c.profile)
+}
+
+// Warnings returns problems found in config files that did not prevent loading
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
+
+// GetProfile returns the name of the active profile
+func (c *Config) GetProfile() string {
+	return c.profile
+}
+
+// ProfileNames returns the names of all profiles in the user config file, sorted
+func (c *Config) ProfileNames() []string {
+	c.userLayer()
+	var names []string
+	for name := range c.user.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the stored settings of a profile, without defaults or other layers
+func (c *Config) Profile(name string) (*Config, error) {
+	c.userLayer()
+	profile, ok := c.user.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' not found", name)
+	}
+	return profile, nil
+}
+
+// CreateProfile adds a profile, copying the settings of another profile when from is set.
+// API keys are never copied.
+func (c *Config) CreateProfile(name string, from string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s', use letters, digits, '-' and '_'", name)
+	}
+
+	return c.update(func(user *userConfig) error {
+		if _, exists := user.Profiles[name]; exists {
+			return fmt.Errorf("profile '%s' already exists", name)
+		}
+
+		profile := &Config{}
+		if from != "" {
+			source, ok := user.Profiles[from]
+			if !ok {
+				return fmt.Errorf("profile '%s' not found", from)
+			}
+			copied := *source
+			copied.OpenRouterKey = ""
+			profile = &copied
+		}
+
+		user.Profiles[name] = profile
+		return nil
+	})
+}
+
+// DeleteProfile removes a profile and its stored API key. The active profile cannot be deleted.
+func (c *Config) DeleteProfile(name string) error {
+	if _, err := c.Profile(name); err != nil {
+		return err
+	}
+	if name == c.profile {
+		return fmt.Errorf("profile '%s' is in use; switch to another profile first", name)
 	}
-	
-	c.CurrentModel = modelID
-	return c.Save()
+
+	if _, err := deleteAPIKey(name); err != nil {
+		return err
+	}
+	return c.update(func(user *userConfig) error {
+		delete(user.Profiles, name)
+		if user.Profile == name {
+			user.Profile = ""
+		}
+		return nil
+	})
+}
+
+// UseProfile makes a profile the default for future commands
+func (c *Config) UseProfile(name string) error {
+	if _, err := c.Profile(name); err != nil {
+		return err
+	}
+	return c.update(func(user *userConfig) error {
+		user.Profile = name
+		if name == defaultProfile {
+			user.Profile = ""
+		}
+		return nil
+	})
 }
 
 // getConfigPath returns the path to the config file
@@ -60,48 +908,156 @@ func getConfigPath() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	
-	configDir := filepath.Join(homeDir, ".photon")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return "", err
-	}
-	
-	return filepath.Join(configDir, "config.json"), nil
+
+	return filepath.Join(homeDir, ".photon", "config.json"), nil
 }
 
-// Save saves the config to disk
-func (c *Config) Save() error {
-	configPath, err := getConfigPath()
+// findProjectConfig searches the working directory and its parents for a project config file
+func findProjectConfig() string {
+	dir, err := os.Getwd()
 	if err != nil {
-		return err
+		return ""
 	}
-	
-	data, err := json.MarshalIndent(c, "", "  ")
-	if err != nil {
-		return err
+
+	for {
+		for _, name := range projectConfigNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// selectProfile picks the active profile: --profile, then PHOTON_PROFILE, then the config file
+func selectProfile(user *userConfig) (string, error) {
+	name, source := profileOverride, "--profile"
+	if name == "" {
+		name, source = os.Getenv("PHOTON_PROFILE"), "PHOTON_PROFILE"
+	}
+	if name == "" {
+		name, source = user.Profile, "the config file"
 	}
-	
-	return os.WriteFile(configPath, data, 0644)
+	if name == "" || name == defaultProfile {
+		return defaultProfile, nil
+	}
+
+	if _, ok := user.Profiles[name]; !ok {
+		return "", fmt.Errorf("profile '%s' from %s not found; run 'ptn profile list'", name, source)
+	}
+	return name, nil
 }
 
-// LoadConfig loads configuration from file and environment
+// merge copies every value set in layer over c and records its origin
+func (c *Config) merge(layer *Config, origin string) {
+	for _, field := range configFields {
+		if value := field.get(layer); value != "" {
+			field.set(c, value)
+			c.origins[field.Key] = origin
+		}
+	}
+
+	for name, theme := range layer.Themes {
+		if c.Themes == nil {
+			c.Themes = make(map[string]pkg.Theme)
+		}
+		c.Themes[name] = theme
+		c.origins["themes."+name] = origin
+	}
+
+	// Rules add up across layers, so a project can add rules without repeating the user's
+	if len(layer.RedactRules) > 0 {
+		c.RedactRules = append(c.RedactRules, layer.RedactRules...)
+		c.origins["redact_rules"] = origin
+	}
+	// Route rules of higher layers come first, since the first matching rule wins
+	if len(layer.RouteRules) > 0 {
+		c.RouteRules = append(slices.Clone(layer.RouteRules), c.RouteRules...)
+		c.origins["route_rules"] = origin
+	}
+}
+
+// LoadConfig loads the active profile's configuration from, in increasing precedence: defaults,
+// the user config file, the stored API key, the nearest project config file, PHOTON_*
+// environment variables and flags
 func LoadConfig() (*Config, error) {
 	config := &Config{
 		CurrentModel: pkg.GetDefaultModel(),
+		user:         &userConfig{Profiles: make(map[string]*Config)},
+		origins:      make(map[string]string),
 	}
-	
-	// Try to load from config file
+
 	configPath, err := getConfigPath()
-	if err == nil {
-		if data, err := os.ReadFile(configPath); err == nil {
-			json.Unmarshal(data, config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(configPath); err == nil {
+		user, warnings, err := readUserConfig(configPath)
+		if err != nil {
+			return nil, err
 		}
+		config.user = user
+		config.warnings = append(config.warnings, warnings...)
 	}
-	
-	// Always prefer environment variable for API key
-	if envKey := os.Getenv("PHOTON_OPEN_ROUTER_KEY"); envKey != "" {
-		config.OpenRouterKey = envKey
+
+	config.profile, err = selectProfile(config.user)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile, ok := config.user.Profiles[config.profile]; ok {
+		layer := *profile
+		// A plain-text key in a file other users can read is refused, not used
+		if layer.OpenRouterKey != "" {
+			if err := checkPrivateFile(configPath); err != nil {
+				layer.OpenRouterKey = ""
+				config.credentialsErr = err
+			}
+		}
+		config.merge(&layer, originUser)
+	}
+
+	// A key saved by 'ptn auth login' wins over one kept in the user config file
+	if key, backend, err := loadAPIKey(config.profile); err != nil {
+		config.credentialsErr = err
+	} else if key != "" {
+		config.OpenRouterKey = key
+		config.origins["openrouter_key"] = originCredentials
+		if backend == backendKeyring {
+			config.origins["openrouter_key"] = originKeyring
+		}
+	}
+
+	// Merge the project config over the user config
+	if path := findProjectConfig(); path != "" {
+		project, warnings, err := readProjectConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		config.warnings = append(config.warnings, warnings...)
+		config.projectPath = path
+		config.merge(project, originProject)
+	}
+
+	// Environment variables and flags win over both files
+	for _, field := range configFields {
+		if value := os.Getenv(field.Env); value != "" {
+			field.set(config, value)
+			config.origins[field.Key] = originEnv
+		}
+	}
+	for _, field := range configFields {
+		if value, ok := flagOverrides[field.Flag]; ok && field.Flag != "" {
+			field.set(config, value)
+			config.origins[field.Key] = originFlag
+		}
 	}
-	
+
 	return config, nil
 }
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage configuration",
+	Long: "Inspect and change photon's configuration, which is layered from defaults, the user config, project config files, PHOTON_* environment variables and flags.\n\n" +
+		"Project config files (.photon.json or .photon.toml in the working directory or a parent) may only set " +
+		"current_model, template, theme, themes, output, lang, route_rules, notes_dir, note_filename and daily_note; " +
+		"other keys are ignored with a warning.",
+}
+
+var showSecret bool
+
+var configGetCmd = &cobra.Command{
+	Use:               "get <key>",
+	Short:             "Print a config value",
+	Long:              "Print the effective value of a config key. Secrets are masked unless --show-secret is given.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigKeys,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		field, err := findConfigField(args[0])
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		value := field.get(config)
+		if value == "" {
+			value = configDefault(field.Key)
+		}
+		if field.Secret && !showSecret {
+			value = maskSecret(value)
+		}
+		fmt.Println(value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:               "set <key> <value>",
+	Short:             "Set a config value",
+	Long:              "Validate a value and store it in the user config file",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeConfigSet,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		if err := config.SetValue(args[0], args[1]); err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"Set ") + pkg.YellowBold(args[0]))
+		warnIfOverridden(config, args[0])
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:               "unset <key>",
+	Short:             "Remove a config value",
+	Long:              "Remove a key from the user config file so its default applies again",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigKeys,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		if err := config.UnsetValue(args[0]); err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"Unset ") + pkg.YellowBold(args[0]))
+		warnIfOverridden(config, args[0])
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List config keys",
+	Long:  "List every config key with its type, current value and description",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		for _, field := range configFields {
+			value := field.get(config)
+			if field.Secret {
+				value = maskSecret(value)
+			}
+			if value == "" {
+				value = pkg.Muted(configDefault(field.Key) + " (default)")
+			}
+
+			fmt.Printf("%s %s %s\n", pkg.YellowBold(fmt.Sprintf("%-16s", field.Key)), pkg.Cyan(fmt.Sprintf("%-9s", field.Type)), value)
+			fmt.Printf("%s %s\n", strings.Repeat(" ", 26), pkg.Muted(field.Description))
+		}
+		fmt.Println()
+		fmt.Println(pkg.Muted("Custom themes are defined under \"themes\", extra redaction rules under \"redact_rules\" and auto model routing under \"route_rules\"; use 'ptn config edit' to change them."))
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the user config file",
+	Long:  "Open the user config file in $VISUAL or $EDITOR and validate it afterwards",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath, err := getConfigPath()
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		// Create the file first so the editor opens a valid, versioned config
+		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+			if err := new(Config).update(func(*userConfig) error { return nil }); err != nil {
+				fmt.Println(pkg.RedBold("Error creating config: ") + err.Error())
+				os.Exit(1)
+			}
+		}
+
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+		}
+
+		// The editor setting may include arguments, such as "code --wait"
+		parts := strings.Fields(editor)
+		editCmd := exec.Command(parts[0], append(parts[1:], configPath)...)
+		editCmd.Stdin = os.Stdin
+		editCmd.Stdout = os.Stdout
+		editCmd.Stderr = os.Stderr
+		if err := editCmd.Run(); err != nil {
+			fmt.Println(pkg.RedBold("Error running editor: ") + err.Error())
+			os.Exit(1)
+		}
+
+		user, warnings, err := readUserConfig(configPath)
+		if err != nil {
+			fmt.Println(pkg.RedBold("Config is invalid: ") + err.Error())
+			os.Exit(1)
+		}
+		for _, warning := range warnings {
+			fmt.Println(pkg.YellowBold("Warning: ") + warning)
+		}
+		for name, profile := range user.Profiles {
+			for _, field := range configFields {
+				if value := field.get(profile); value != "" {
+					if err := field.validate(profile, value); err != nil {
+						fmt.Println(pkg.RedBold("Config is invalid: ") + "profile " + name + ": " + field.Key + ": " + err.Error())
+						os.Exit(1)
+					}
+				}
+			}
+		}
+		fmt.Println(pkg.GreenBold(pkg.Icon("✅") + "Config saved and valid"))
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print config file paths",
+	Long:  "Print the user config file path, and the project config file in effect if there is one",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath, err := getConfigPath()
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(configPath)
+		if project := findProjectConfig(); project != "" {
+			fmt.Println(project)
+		}
+	},
+}
+
+var showOrigin bool
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration",
+	Long:  "Display the effective configuration after all layers are merged. Use --origin to see which layer each value came from.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		fmt.Println(pkg.CyanBold(pkg.Icon("⚙️ ") + "Configuration:"))
+		fmt.Println()
+
+		printConfigLine("profile", config.GetProfile(), describeProfileOrigin())
+		for _, field := range configFields {
+			value := field.get(config)
+			if field.Secret {
+				value = maskSecret(value)
+			}
+			if value == "" {
+				value = pkg.Muted("(not set)")
+			}
+			printConfigLine(field.Key, value, config.describeLayer(field.Key))
+		}
+
+		var themes []string
+		for name := range config.Themes {
+			themes = append(themes, name)
+		}
+		sort.Strings(themes)
+		for _, name := range themes {
+			printConfigLine("themes."+name, "(custom theme)", config.describeLayer("themes."+name))
+		}
+		if len(config.RedactRules) > 0 {
+			printConfigLine("redact_rules", plural(len(config.RedactRules), "custom rule"), config.describeLayer("redact_rules"))
+		}
+		if len(config.RouteRules) > 0 {
+			printConfigLine("route_rules", plural(len(config.RouteRules), "custom rule"), config.describeLayer("route_rules"))
+		}
+
+		if showOrigin {
+			fmt.Println()
+			userPath, _ := getConfigPath()
+			fmt.Println(pkg.Muted("user:    " + userPath))
+			if config.projectPath != "" {
+				fmt.Println(pkg.Muted("project: " + config.projectPath))
+			} else {
+				fmt.Println(pkg.Muted("project: (no " + strings.Join(projectConfigNames, " or ") + " found)"))
+			}
+		}
+	},
+}
+
+// mustLoadConfig loads the config or exits with an error message
+func mustLoadConfig() *Config {
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Println(pkg.RedBold("Error loading config: ") + err.Error())
+		os.Exit(1)
+	}
+	return config
+}
+
+// configDefault returns the value a key has when it is not set
+func configDefault(key string) string {
+	switch key {
+	case "current_model":
+		return pkg.GetDefaultModel()
+	case "template":
+		return pkg.GetDefaultTemplate()
+	case "theme":
+		return pkg.DefaultThemeName
+	case "timeout":
+		return pkg.DefaultTimeout.String()
+	case "base_url":
+		return pkg.DefaultBaseURL
+	case "connect_timeout":
+		return pkg.DefaultConnectTimeout.String()
+	case "read_timeout":
+		return pkg.DefaultReadTimeout.String()
+	case "output":
+		return pkg.DefaultOutputFormat
+	case "lang":
+		return pkg.DefaultLanguage
+	case "emoji", "redact":
+		return "true"
+	case "history":
+		return "false"
+	case "notes_dir":
+		return "."
+	case "note_filename":
+		return pkg.DefaultNoteFilename
+	case "daily_note":
+		return pkg.DefaultDailyNote
+	}
+	return ""
+}
+
+// warnIfOverridden tells the user when a higher layer hides the user config value
+func warnIfOverridden(c *Config, key string) {
+	if origin := c.Origin(key); origin == originProject || origin == originEnv || origin == originFlag {
+		fmt.Println(pkg.YellowBold("Note: ") + "this is overridden by " + c.describeLayer(key))
+	}
+}
+
+// describeProfileOrigin explains how the active profile was selected
+func describeProfileOrigin() string {
+	switch {
+	case profileOverride != "":
+		return "flag --profile"
+	case os.Getenv("PHOTON_PROFILE") != "":
+		return "env PHOTON_PROFILE"
+	}
+	return "user config"
+}
+
+// printConfigLine prints a key and value, followed by its origin when --origin is set
+func printConfigLine(key string, value string, origin string) {
+	line := fmt.Sprintf("%s %s", pkg.YellowBold(fmt.Sprintf("%-16s", key)), pkg.White(value))
+	if showOrigin {
+		line += "  " + pkg.Muted("← "+origin)
+	}
+	fmt.Println(line)
+}
+
+// describeLayer explains where a value came from, naming the env var, flag or file
+func (c *Config) describeLayer(key string) string {
+	origin := c.Origin(key)
+	for _, field := range configFields {
+		if field.Key != key {
+			continue
+		}
+		switch origin {
+		case originEnv:
+			return "env " + field.Env
+		case originFlag:
+			return "flag --" + field.Flag
+		}
+	}
+
+	switch origin {
+	case originProject:
+		return "project " + c.projectPath
+	case originKeyring:
+		return "Secret Service keyring"
+	case originCredentials:
+		path, _ := getCredentialsPath(c.profile)
+		return "encrypted file " + path
+	case originUser:
+		path, _ := getConfigPath()
+		return "user " + path + " (profile " + c.profile + ")"
+	}
+	return origin
+}
+
+// maskSecret hides all but the last four characters of a secret
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+	return strings.Repeat("*", 8) + secret[len(secret)-4:]
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configPathCmd)
+
+	configShowCmd.Flags().BoolVar(&showOrigin, "origin", false, "Show which layer each value came from")
+	configGetCmd.Flags().BoolVar(&showSecret, "show-secret", false, "Print secrets unmasked")
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// configError is a problem in a config file, located by line and column when known
+type configError struct {
+	Path string
+	Line int
+	Col  int
+	Key  string
+	Err  error
+}
+
+func (e *configError) Error() string {
+	location := e.Path
+	if e.Line > 0 {
+		location += fmt.Sprintf(":%d:%d", e.Line, e.Col)
+	}
+	if e.Key != "" {
+		return fmt.Sprintf("%s: %s: %s", location, e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", location, e.Err)
+}
+
+func (e *configError) Unwrap() error {
+	return e.Err
+}
+
+// configSource is a decoded config file along with what is needed to report positions in it
+type configSource struct {
+	path string
+	data []byte
+	raw  map[string]interface{}
+	// keys maps dotted key paths to their byte offset; only known for JSON files
+	keys map[string]int
+}
+
+// decodeConfigFile reads a JSON or TOML config file into a generic map. Both formats
+// are decoded generically so they share the JSON keys and migrations.
+func decodeConfigFile(path string) (*configSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	source := &configSource{path: path, data: data, keys: make(map[string]int)}
+
+	if filepath.Ext(path) == ".toml" {
+		if _, err := toml.Decode(string(data), &source.raw); err != nil {
+			var parseErr toml.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, &configError{Path: path, Line: parseErr.Position.Line, Col: parseErr.Position.Col, Err: errors.New(parseErr.Message)}
+			}
+			return nil, &configError{Path: path, Err: err}
+		}
+		return source, nil
+	}
+
+	if err := json.Unmarshal(data, &source.raw); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		// Offsets count the bytes read, so the offending character is the one before
+		switch {
+		case errors.As(err, &syntaxErr):
+			return nil, source.errorAt(max(syntaxErr.Offset-1, 0), "", errors.New(strings.TrimPrefix(syntaxErr.Error(), "json: ")))
+		case errors.As(err, &typeErr):
+			return nil, source.errorAt(max(typeErr.Offset-1, 0), "", fmt.Errorf("expected an object, got %s", typeErr.Value))
+		}
+		return nil, &configError{Path: path, Err: err}
+	}
+	source.keys = jsonKeyOffsets(data)
+	return source, nil
+}
+
+// errorAt builds a configError for a byte offset in the file
+func (s *configSource) errorAt(offset int64, key string, err error) *configError {
+	line, col := 1, 1
+	for _, b := range s.data[:min(int(offset), len(s.data))] {
+		if b == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return &configError{Path: s.path, Line: line, Col: col, Key: key, Err: err}
+}
+
+// errorAtKey builds a configError for a dotted key path, with its position when known.
+// Keys migrated into the default profile are reported at their original location.
+func (s *configSource) errorAtKey(key string, err error) *configError {
+	for _, candidate := range []string{key, strings.TrimPrefix(key, "profiles."+defaultProfile+".")} {
+		if offset, ok := s.keys[candidate]; ok {
+			return s.errorAt(int64(offset), candidate, err)
+		}
+	}
+	return &configError{Path: s.path, Key: key, Err: err}
+}
+
+// jsonKeyOffsets returns the byte offset of every object key in a JSON document, by dotted path
+func jsonKeyOffsets(data []byte) map[string]int {
+	offsets := make(map[string]int)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	var walk func(prefix string) error
+	walk = func(prefix string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				token, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := token.(string)
+				path := key
+				if prefix != "" {
+					path = prefix + "." + key
+				}
+				// InputOffset is the end of the key token, so step back over the key and quotes
+				offsets[path] = int(decoder.InputOffset()) - len(key) - 2
+				if err := walk(path); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+			return err
+		case json.Delim('['):
+			for decoder.More() {
+				if err := walk(prefix); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+			return err
+		}
+		return nil
+	}
+
+	walk("")
+	return offsets
+}
+
+// convert decodes the migrated config map into its typed form, reporting values of the wrong type
+func (s *configSource) convert(v interface{}) error {
+	data, err := json.Marshal(s.raw)
+	if err != nil {
+		return &configError{Path: s.path, Err: err}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return s.errorAtKey(typeErr.Field, fmt.Errorf("expected %s, got %s", typeErr.Type, typeErr.Value))
+		}
+		return &configError{Path: s.path, Err: err}
+	}
+	return nil
+}
+
+// settingKeys returns the keys allowed in a flat config layer or profile
+func settingKeys() map[string]bool {
+	keys := map[string]bool{"themes": true, "redact_rules": true, "route_rules": true}
+	for _, field := range configFields {
+		keys[field.Key] = true
+	}
+	return keys
+}
+
+// themeKeys returns the color roles allowed in a custom theme
+func themeKeys() map[string]bool {
+	keys := make(map[string]bool)
+	themeType := reflect.TypeOf(pkg.Theme{})
+	for i := 0; i < themeType.NumField(); i++ {
+		if name, _, _ := strings.Cut(themeType.Field(i).Tag.Get("json"), ","); name != "" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// unknownSettings warns about keys in a flat layer or profile that photon does not use
+func (s *configSource) unknownSettings(prefix string, layer map[string]interface{}) []string {
+	var warnings []string
+	allowed, roles := settingKeys(), themeKeys()
+
+	for key, value := range layer {
+		if !allowed[key] {
+			warnings = append(warnings, s.errorAtKey(prefix+key, errors.New("unknown key, ignored")).Error())
+			continue
+		}
+		if key != "themes" {
+			continue
+		}
+		themes, _ := value.(map[string]interface{})
+		for name, theme := range themes {
+			colors, _ := theme.(map[string]interface{})
+			for role := range colors {
+				if !roles[role] {
+					warnings = append(warnings, s.errorAtKey(prefix+"themes."+name+"."+role, errors.New("unknown theme color, ignored")).Error())
+				}
+			}
+		}
+	}
+
+	sort.Strings(warnings)
+	return warnings
+}
+
+// projectKeys are the settings a project config file may change. A project file can come
+// with any cloned repository, so settings that decide where queries and the API key are
+// sent, how connections are trusted or whether queries are redacted stay user-only.
+var projectKeys = map[string]bool{
+	"current_model": true,
+	"template":      true,
+	"theme":         true,
+	"themes":        true,
+	"output":        true,
+	"lang":          true,
+	"route_rules":   true,
+	"notes_dir":     true,
+	"note_filename": true,
+	"daily_note":    true,
+}
+
+// readProjectConfig decodes a project config file and returns warnings for keys it does
+// not recognize or that only the user config may set, which are ignored
+func readProjectConfig(path string) (*Config, []string, error) {
+	source, err := decodeConfigFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Flat layers have no profiles, so only the legacy key renames apply
+	if err := migrateV1ToV2(source.raw); err != nil {
+		return nil, nil, &configError{Path: path, Err: err}
+	}
+	delete(source.raw, "version")
+	warnings := source.unknownSettings("", source.raw)
+
+	known := settingKeys()
+	for key := range source.raw {
+		if known[key] && !projectKeys[key] {
+			warnings = append(warnings, source.errorAtKey(key, errors.New("only allowed in the user config, ignored")).Error())
+		}
+		if !projectKeys[key] {
+			delete(source.raw, key)
+		}
+	}
+	sort.Strings(warnings)
+
+	config := &Config{}
+	if err := source.convert(config); err != nil {
+		return nil, nil, err
+	}
+	return config, warnings, nil
+}
+
+// readUserConfig decodes the user config file, migrating older schema versions, and
+// returns warnings for keys it does not recognize
+func readUserConfig(path string) (*userConfig, []string, error) {
+	source, err := decodeConfigFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := configVersion(source.raw); err != nil {
+		return nil, nil, source.errorAtKey("version", err)
+	}
+	if err := migrateConfig(source.raw); err != nil {
+		return nil, nil, &configError{Path: path, Err: err}
+	}
+
+	var warnings []string
+	for key := range source.raw {
+		if key != "version" && key != "profile" && key != "profiles" {
+			warnings = append(warnings, source.errorAtKey(key, errors.New("unknown key, ignored")).Error())
+		}
+	}
+	profiles, _ := source.raw["profiles"].(map[string]interface{})
+	for name, profile := range profiles {
+		if layer, ok := profile.(map[string]interface{}); ok {
+			warnings = append(warnings, source.unknownSettings("profiles."+name+".", layer)...)
+		}
+	}
+	sort.Strings(warnings)
+
+	user := &userConfig{}
+	if err := source.convert(user); err != nil {
+		return nil, nil, err
+	}
+	if user.Profiles == nil {
+		user.Profiles = make(map[string]*Config)
+	}
+	for name, profile := range user.Profiles {
+		if profile == nil {
+			user.Profiles[name] = &Config{}
+		}
+	}
+	return user, warnings, nil
+}
+
+// ensureConfigDir creates the config directory with private permissions. Only
+// writers call it, so reading config never touches the file system.
+func ensureConfigDir() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(configPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	// The directory may predate private permissions, so tighten it explicitly
+	if err := os.Chmod(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// writeFileAtomic replaces a file by writing a temporary file next to it and renaming
+// it into place, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing after a successful rename fails harmlessly
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// update applies a change to the user config file under an exclusive lock. The file is
+// re-read inside the lock so concurrent ptn invocations do not lose each other's changes.
+// Values from project files, environment variables and flags are never written back.
+func (c *Config) update(change func(*userConfig) error) error {
+	if _, err := ensureConfigDir(); err != nil {
+		return err
+	}
+	configPath, err := getConfigPath()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(configPath + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	user := &userConfig{Profiles: make(map[string]*Config)}
+	if _, err := os.Stat(configPath); err == nil {
+		if user, _, err = readUserConfig(configPath); err != nil {
+			return err
+		}
+	}
+
+	if err := change(user); err != nil {
+		return err
+	}
+	user.Version = currentConfigVersion
+
+	data, err := json.MarshalIndent(user, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(configPath, data, 0600); err != nil {
+		return err
+	}
+
+	c.user = user
+	return nil
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDecodeConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		wantLine int
+		wantCol  int
+		wantErr  string
+	}{
+		{
+			name:     "trailing comma",
+			file:     "config.json",
+			content:  "{\n  \"version\": 3,\n  \"profiles\": {\n    \"default\": {\"template\": \"brief\",}\n  }\n}",
+			wantLine: 4,
+			wantCol:  37,
+			wantErr:  "invalid character '}'",
+		},
+		{
+			name:     "not an object",
+			file:     "config.json",
+			content:  "[1, 2]",
+			wantLine: 1,
+			wantCol:  1,
+			wantErr:  "expected an object, got array",
+		},
+		{
+			name:     "empty JSON",
+			file:     "config.json",
+			content:  "",
+			wantLine: 1,
+			wantCol:  1,
+			wantErr:  "unexpected end of JSON input",
+		},
+		{
+			name:     "TOML missing value",
+			file:     ".photon.toml",
+			content:  "template = \"brief\"\ntheme = \n",
+			wantLine: 2,
+			wantCol:  9,
+			wantErr:  "expected value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			writeTestFile(t, path, tt.content)
+
+			_, err := decodeConfigFile(path)
+			var configErr *configError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("decodeConfigFile() error = %v, want a configError", err)
+			}
+			if configErr.Line != tt.wantLine || configErr.Col != tt.wantCol {
+				t.Errorf("position = %d:%d, want %d:%d", configErr.Line, configErr.Col, tt.wantLine, tt.wantCol)
+			}
+			if !strings.HasPrefix(err.Error(), path+":") || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want %q at %s", err, tt.wantErr, path)
+			}
+		})
+	}
+}
+
+func TestReadUserConfigPositions(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantErr     string
+		wantWarning string
+	}{
+		{
+			name:    "wrong type in a profile",
+			content: "{\n  \"version\": 3,\n  \"profiles\": {\n    \"default\": {\n      \"template\": 5\n    }\n  }\n}",
+			wantErr: ":5:7: profiles.default.template: expected string, got number",
+		},
+		{
+			name:        "unknown key in a profile",
+			content:     "{\n  \"version\": 3,\n  \"profiles\": {\"work\": {\"colour\": \"red\"}}\n}",
+			wantWarning: ":3:25: profiles.work.colour: unknown key, ignored",
+		},
+		{
+			name:        "unknown key before migration is reported where it was written",
+			content:     "{\n  \"colour\": \"red\",\n  \"template\": \"brief\"\n}",
+			wantWarning: ":2:3: colour: unknown key, ignored",
+		},
+		{
+			name:    "newer version",
+			content: "{\"version\": 9}",
+			wantErr: ":1:2: version: config version 9 is newer",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			writeTestFile(t, path, tt.content)
+
+			_, warnings, err := readUserConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readUserConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], tt.wantWarning) {
+				t.Errorf("warnings = %q, want one with %q", warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		perm     os.FileMode
+	}{
+		{"new file", "", 0600},
+		{"replaces a file", "old contents that are longer", 0600},
+		{"sets the mode", "", 0640},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.json")
+			if tt.existing != "" {
+				writeTestFile(t, path, tt.existing)
+			}
+
+			if err := writeFileAtomic(path, []byte("new"), tt.perm); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "new" {
+				t.Errorf("contents = %q, want %q", data, "new")
+			}
+			info, _ := os.Stat(path)
+			if info.Mode().Perm() != tt.perm {
+				t.Errorf("mode = %04o, want %04o", info.Mode().Perm(), tt.perm)
+			}
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 1 {
+				t.Errorf("directory holds %d files, want only the written one", len(entries))
+			}
+		})
+	}
+
+	if err := writeFileAtomic(filepath.Join(t.TempDir(), "missing", "config.json"), nil, 0600); err == nil {
+		t.Error("writeFileAtomic() into a missing directory returned no error")
+	}
+}
+
+func TestConfigUpdate(t *testing.T) {
+	t.Run("keeps changes made since loading", func(t *testing.T) {
+		setupConfigEnv(t, `{"version": 3, "profiles": {"default": {"theme": "light"}}}`, "")
+		first, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := first.SetValue("template", "brief"); err != nil {
+			t.Fatal(err)
+		}
+		if err := second.SetValue("output", "json"); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, want := range map[string]string{"theme": "light", "template": "brief", "output": "json"} {
+			field, _ := findConfigField(key)
+			if got := field.get(loaded); got != want {
+				t.Errorf("%s = %q, want %q", key, got, want)
+			}
+		}
+	})
+
+	t.Run("does not write other layers back", func(t *testing.T) {
+		setupConfigEnv(t, "", `{"template": "explain"}`)
+		t.Setenv("PHOTON_OUTPUT", "json")
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := config.SetValue("theme", "light"); err != nil {
+			t.Fatal(err)
+		}
+
+		path, _ := getConfigPath()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), `"light"`) {
+			t.Errorf("config file %s does not hold the new theme", data)
+		}
+		if strings.Contains(string(data), "explain") || strings.Contains(string(data), `"output"`) {
+			t.Errorf("config file %s holds project or environment values", data)
+		}
+		if err := checkPrivateFile(path); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("concurrent updates are all kept", func(t *testing.T) {
+		setupConfigEnv(t, "", "")
+		names := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+		var wg sync.WaitGroup
+		for _, name := range names {
+			wg.Add(1)
+			// Each writer loads its own config, like separate ptn invocations
+			go func() {
+				defer wg.Done()
+				config, err := LoadConfig()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				err = config.update(func(user *userConfig) error {
+					user.profileLayer(name).Template = "brief"
+					return nil
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		path, _ := getConfigPath()
+		user, _, err := readUserConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if user.Profiles[name] == nil {
+				t.Errorf("profile %s was lost", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// currentConfigVersion is the schema version written by Save. Files without a
+// version field predate versioning and are treated as version 1.
+const currentConfigVersion = 3
+
+// configMigrations upgrade a decoded config file one version at a time;
+// configMigrations[i] migrates version i+1 to version i+2
+var configMigrations = []func(map[string]interface{}) error{
+	migrateV1ToV2,
+	migrateV2ToV3,
+}
+
+// configVersion returns the schema version of a decoded config file, or an error if
+// this version of photon cannot read it
+func configVersion(raw map[string]interface{}) (int, error) {
+	version := 1
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	} else if v, ok := raw["version"].(int64); ok {
+		version = int(v)
+	}
+
+	if version < 1 {
+		return 0, fmt.Errorf("config version %d is not valid, expected 1 to %d", version, currentConfigVersion)
+	}
+	if version > currentConfigVersion {
+		return 0, fmt.Errorf("config version %d is newer than this version of photon supports (%d)", version, currentConfigVersion)
+	}
+	return version, nil
+}
+
+// migrateConfig upgrades a decoded config file to the current schema version in place
+func migrateConfig(raw map[string]interface{}) error {
+	version, err := configVersion(raw)
+	if err != nil {
+		return err
+	}
+
+	for ; version < currentConfigVersion; version++ {
+		if err := configMigrations[version-1](raw); err != nil {
+			return fmt.Errorf("migrating config from version %d: %w", version, err)
+		}
+	}
+
+	raw["version"] = currentConfigVersion
+	return nil
+}
+
+// migrateV1ToV2 renames the legacy "model" key and converts OpenRouter API names
+// such as "deepseek/deepseek-chat:free" into model IDs
+func migrateV1ToV2(raw map[string]interface{}) error {
+	if legacy, ok := raw["model"]; ok {
+		if _, exists := raw["current_model"]; !exists {
+			raw["current_model"] = legacy
+		}
+		delete(raw, "model")
+	}
+
+	if name, ok := raw["current_model"].(string); ok && name != "" && !pkg.ValidateModel(name) {
+		if model, err := pkg.GetModelByAPIName(name); err == nil {
+			raw["current_model"] = model.ID
+		}
+	}
+
+	return nil
+}
+
+// migrateV2ToV3 moves the flat settings into the default profile
+func migrateV2ToV3(raw map[string]interface{}) error {
+	profile := make(map[string]interface{})
+	for key, value := range raw {
+		if key == "version" {
+			continue
+		}
+		profile[key] = value
+		delete(raw, key)
+	}
+
+	raw["profiles"] = map[string]interface{}{defaultProfile: profile}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+func TestMigrateV1ToV2(t *testing.T) {
+	model, err := pkg.GetModel(pkg.GetDefaultModel())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		raw  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "legacy model key is renamed",
+			raw:  map[string]interface{}{"model": model.ID},
+			want: map[string]interface{}{"current_model": model.ID},
+		},
+		{
+			name: "current_model wins over the legacy key",
+			raw:  map[string]interface{}{"model": "old", "current_model": model.ID},
+			want: map[string]interface{}{"current_model": model.ID},
+		},
+		{
+			name: "API name becomes the model ID",
+			raw:  map[string]interface{}{"current_model": model.APIName},
+			want: map[string]interface{}{"current_model": model.ID},
+		},
+		{
+			name: "unknown model is left for validation",
+			raw:  map[string]interface{}{"current_model": "no-such-model"},
+			want: map[string]interface{}{"current_model": "no-such-model"},
+		},
+		{
+			name: "other keys are untouched",
+			raw:  map[string]interface{}{"template": "brief"},
+			want: map[string]interface{}{"template": "brief"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := migrateV1ToV2(tt.raw); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.raw, tt.want) {
+				t.Errorf("migrateV1ToV2() = %v, want %v", tt.raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateV2ToV3(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "settings move into the default profile",
+			raw:  map[string]interface{}{"version": float64(2), "template": "brief", "themes": map[string]interface{}{"x": map[string]interface{}{}}},
+			want: map[string]interface{}{
+				"version":  float64(2),
+				"profiles": map[string]interface{}{defaultProfile: map[string]interface{}{"template": "brief", "themes": map[string]interface{}{"x": map[string]interface{}{}}}},
+			},
+		},
+		{
+			name: "empty file gets an empty default profile",
+			raw:  map[string]interface{}{},
+			want: map[string]interface{}{"profiles": map[string]interface{}{defaultProfile: map[string]interface{}{}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := migrateV2ToV3(tt.raw); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.raw, tt.want) {
+				t.Errorf("migrateV2ToV3() = %v, want %v", tt.raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     map[string]interface{}
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "unversioned file runs every migration",
+			raw:  map[string]interface{}{"model": "deepseek-v3"},
+			want: map[string]interface{}{
+				"version":  currentConfigVersion,
+				"profiles": map[string]interface{}{defaultProfile: map[string]interface{}{"current_model": "deepseek-v3"}},
+			},
+		},
+		{
+			name: "current version is unchanged",
+			raw:  map[string]interface{}{"version": float64(currentConfigVersion), "profiles": map[string]interface{}{}},
+			want: map[string]interface{}{"version": currentConfigVersion, "profiles": map[string]interface{}{}},
+		},
+		{
+			name:    "newer version",
+			raw:     map[string]interface{}{"version": float64(currentConfigVersion + 1)},
+			wantErr: "newer than this version of photon supports",
+		},
+		{
+			name:    "version below 1",
+			raw:     map[string]interface{}{"version": float64(0)},
+			wantErr: "config version 0 is not valid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := migrateConfig(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("migrateConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.raw, tt.want) {
+				t.Errorf("migrateConfig() = %v, want %v", tt.raw, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// setupConfigEnv gives LoadConfig a temporary home with the given user config and a working
+// directory with the given project config, ignoring the real environment, keyring and flags.
+// Empty contents leave the file out.
+func setupConfigEnv(t *testing.T, user string, project string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "")
+	t.Setenv("PHOTON_PROFILE", "")
+	for _, field := range configFields {
+		t.Setenv(field.Env, "")
+	}
+	saved := flagOverrides
+	flagOverrides = map[string]string{}
+	t.Cleanup(func() { flagOverrides = saved })
+
+	if user != "" {
+		writeTestFile(t, filepath.Join(home, ".photon", "config.json"), user)
+	}
+	dir := filepath.Join(home, "project")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if project != "" {
+		writeTestFile(t, filepath.Join(dir, ".photon.json"), project)
+	}
+	t.Chdir(dir)
+}
+
+// writeTestFile writes a private file, creating its directory
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	const user = `{"version": 3, "profile": "work", "profiles": {
+		"default": {"template": "brief", "theme": "light"},
+		"work": {"template": "deep-dive", "output": "json"}
+	}}`
+
+	tests := []struct {
+		name       string
+		user       string
+		project    string
+		env        map[string]string
+		flags      map[string]string
+		key        string
+		want       string
+		wantOrigin string
+	}{
+		{
+			name:       "default",
+			key:        "template",
+			want:       "",
+			wantOrigin: originDefault,
+		},
+		{
+			name:       "default model",
+			key:        "current_model",
+			want:       pkg.GetDefaultModel(),
+			wantOrigin: originDefault,
+		},
+		{
+			name:       "active profile from the user config",
+			user:       user,
+			key:        "template",
+			want:       "deep-dive",
+			wantOrigin: originUser,
+		},
+		{
+			name:       "PHOTON_PROFILE picks another profile",
+			user:       user,
+			env:        map[string]string{"PHOTON_PROFILE": "default"},
+			key:        "template",
+			want:       "brief",
+			wantOrigin: originUser,
+		},
+		{
+			name:       "project over user",
+			user:       user,
+			project:    `{"template": "explain"}`,
+			key:        "template",
+			want:       "explain",
+			wantOrigin: originProject,
+		},
+		{
+			name:       "user value the project leaves alone",
+			user:       user,
+			project:    `{"template": "explain"}`,
+			key:        "output",
+			want:       "json",
+			wantOrigin: originUser,
+		},
+		{
+			name:       "env over project",
+			user:       user,
+			project:    `{"template": "explain"}`,
+			env:        map[string]string{"PHOTON_TEMPLATE": "research"},
+			key:        "template",
+			want:       "research",
+			wantOrigin: originEnv,
+		},
+		{
+			name:       "flag over env",
+			user:       user,
+			project:    `{"template": "explain"}`,
+			env:        map[string]string{"PHOTON_TEMPLATE": "research"},
+			flags:      map[string]string{"template": "brief"},
+			key:        "template",
+			want:       "brief",
+			wantOrigin: originFlag,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupConfigEnv(t, tt.user, tt.project)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			for name, value := range tt.flags {
+				flagOverrides[name] = value
+			}
+
+			config, err := LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			field, err := findConfigField(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := field.get(config); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+			}
+			if got := config.Origin(tt.key); got != tt.wantOrigin {
+				t.Errorf("origin of %s = %q, want %q", tt.key, got, tt.wantOrigin)
+			}
+		})
+	}
+}
+
+func TestLoadConfigProjectAllowlist(t *testing.T) {
+	const user = `{"version": 3, "profiles": {"default": {"base_url": "https://user.example/api/v1"}}}`
+
+	tests := []struct {
+		name         string
+		project      string
+		key          string
+		want         string
+		wantOrigin   string
+		wantWarnings []string
+	}{
+		{
+			name:       "allowed key applies",
+			project:    `{"template": "brief"}`,
+			key:        "template",
+			want:       "brief",
+			wantOrigin: originProject,
+		},
+		{
+			name:         "base_url stays with the user",
+			project:      `{"base_url": "https://evil.example/api/v1"}`,
+			key:          "base_url",
+			want:         "https://user.example/api/v1",
+			wantOrigin:   originUser,
+			wantWarnings: []string{"base_url", "only allowed in the user config"},
+		},
+		{
+			name:         "api key is ignored",
+			project:      `{"openrouter_key": "sk-project"}`,
+			key:          "openrouter_key",
+			want:         "",
+			wantOrigin:   originDefault,
+			wantWarnings: []string{"openrouter_key", "only allowed in the user config"},
+		},
+		{
+			name:         "redaction cannot be turned off",
+			project:      `{"redact": false, "redact_rules": [{"name": "x", "pattern": "x"}]}`,
+			key:          "redact",
+			want:         "",
+			wantOrigin:   originDefault,
+			wantWarnings: []string{"redact", "redact_rules", "only allowed in the user config"},
+		},
+		{
+			name:         "legacy key is renamed before the check",
+			project:      `{"model": "gpt-4o"}`,
+			key:          "current_model",
+			want:         "gpt-4o",
+			wantOrigin:   originProject,
+			wantWarnings: nil,
+		},
+		{
+			name:         "unknown key",
+			project:      `{"colour": "red"}`,
+			key:          "theme",
+			want:         "",
+			wantOrigin:   originDefault,
+			wantWarnings: []string{"colour", "unknown key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupConfigEnv(t, user, tt.project)
+			config, err := LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			field, err := findConfigField(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := field.get(config); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+			}
+			if got := config.Origin(tt.key); got != tt.wantOrigin {
+				t.Errorf("origin of %s = %q, want %q", tt.key, got, tt.wantOrigin)
+			}
+
+			warnings := strings.Join(config.Warnings(), "\n")
+			if len(tt.wantWarnings) == 0 && warnings != "" {
+				t.Errorf("unexpected warnings: %s", warnings)
+			}
+			for _, want := range tt.wantWarnings {
+				if !strings.Contains(warnings, want) {
+					t.Errorf("warnings %q do not mention %q", warnings, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigFieldValidation(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{"current_model", pkg.GetDefaultModel(), false},
+		{"current_model", "no-such-model", true},
+		{"template", "brief", false},
+		{"template", "no-such-template", true},
+		{"theme", pkg.DefaultThemeName, false},
+		{"theme", "no-such-theme", true},
+		{"timeout", "30s", false},
+		{"timeout", "0", false},
+		{"timeout", "-1s", true},
+		{"timeout", "soon", true},
+		{"connect_timeout", "5s", false},
+		{"connect_timeout", "0", true},
+		{"base_url", "https://openrouter.ai/api/v1", false},
+		{"base_url", "openrouter.ai", true},
+		{"base_url", "ftp://openrouter.ai", true},
+		{"output", "json", false},
+		{"output", "yaml", true},
+		{"lang", "auto", false},
+		{"lang", "zh", false},
+		{"lang", "klingon", true},
+		{"history", "true", false},
+		{"history", "maybe", true},
+		{"openrouter_key", "sk-or-1234", false},
+		{"openrouter_key", "sk-or 1234", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			field, err := findConfigField(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := field.validate(&Config{}, tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("validate(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindConfigField(t *testing.T) {
+	if _, err := findConfigField("no_such_key"); err == nil {
+		t.Error("findConfigField() of an unknown key returned no error")
+	}
+	for _, field := range configFields {
+		if field.get == nil || field.set == nil || field.validate == nil {
+			t.Errorf("field %s is missing get, set or validate", field.Key)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// Places the API key can be stored
+const (
+	backendKeyring = "keyring"
+	backendFile    = "file"
+)
+
+// Secret Service attributes that identify the stored API key
+const (
+	keyringService = "photon"
+	keyringAccount = "openrouter"
+)
+
+// profileKeyringAccount returns the keyring account holding a profile's API key
+func profileKeyringAccount(profile string) string {
+	if profile == defaultProfile {
+		return keyringAccount
+	}
+	return keyringAccount + "/" + profile
+}
+
+// credentialsFileVersion is the format version of the encrypted credentials file
+const credentialsFileVersion = 1
+
+// credentialsFile is the on-disk format of the encrypted API key fallback
+type credentialsFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// keyringAvailable reports whether the Secret Service keyring can be used through secret-tool
+func keyringAvailable() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+// keyringStore saves a profile's API key in the Secret Service keyring
+func keyringStore(profile string, key string) error {
+	cmd := exec.Command("secret-tool", "store", "--label=Photon OpenRouter API key ("+profile+")", "service", keyringService, "account", profileKeyringAccount(profile))
+	// The secret is passed on stdin so it never shows up in the process list
+	cmd.Stdin = strings.NewReader(key)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool store: %s", strings.TrimSpace(string(out)+" "+err.Error()))
+	}
+	return nil
+}
+
+// keyringLookup returns a profile's API key from the Secret Service keyring, or "" if none is stored
+func keyringLookup(profile string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", keyringService, "account", profileKeyringAccount(profile))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits 1 without output when nothing matches
+		if stderr.Len() == 0 {
+			return "", nil
+		}
+		return "", fmt.Errorf("secret-tool lookup: %s", strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// keyringDelete removes a profile's API key from the Secret Service keyring
+func keyringDelete(profile string) error {
+	if out, err := exec.Command("secret-tool", "clear", "service", keyringService, "account", profileKeyringAccount(profile)).CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool clear: %s", strings.TrimSpace(string(out)+" "+err.Error()))
+	}
+	return nil
+}
+
+// getCredentialsPath returns the path to a profile's encrypted credentials file
+func getCredentialsPath(profile string) (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	name := "credentials"
+	if profile != defaultProfile {
+		name += "." + profile
+	}
+	return filepath.Join(filepath.Dir(configPath), name), nil
+}
+
+// checkPrivateFile refuses files that other users can read or write
+func checkPrivateFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("%s contains an API key but is accessible by other users (mode %04o); run 'chmod 600 %s'", path, perm, path)
+	}
+	return nil
+}
+
+// credentialsKey derives the file encryption key from a random salt and this machine and
+// user, so a copied credentials file cannot be decrypted elsewhere. File permissions
+// remain the main protection; encryption keeps the key out of backups and casual reads.
+func credentialsKey(salt []byte) ([]byte, error) {
+	secret := keyringService
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if id, err := os.ReadFile(path); err == nil {
+			secret += strings.TrimSpace(string(id))
+			break
+		}
+	}
+	if host, err := os.Hostname(); err == nil {
+		secret += host
+	}
+	if u, err := user.Current(); err == nil {
+		secret += u.Uid + u.Username
+	}
+	return hkdf.Key(sha256.New, []byte(secret), salt, "photon credentials", 32)
+}
+
+// newCredentialsCipher returns an AES-GCM cipher keyed for the given salt
+func newCredentialsCipher(salt []byte) (cipher.AEAD, error) {
+	key, err := credentialsKey(salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// fileStore encrypts a profile's API key into its credentials file with 0600 permissions
+func fileStore(profile string, key string) error {
+	if _, err := ensureConfigDir(); err != nil {
+		return err
+	}
+	path, err := getCredentialsPath(profile)
+	if err != nil {
+		return err
+	}
+
+	creds := credentialsFile{Version: credentialsFileVersion, Salt: make([]byte, 16)}
+	if _, err := rand.Read(creds.Salt); err != nil {
+		return err
+	}
+	gcm, err := newCredentialsCipher(creds.Salt)
+	if err != nil {
+		return err
+	}
+	creds.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(creds.Nonce); err != nil {
+		return err
+	}
+	creds.Ciphertext = gcm.Seal(nil, creds.Nonce, []byte(key), nil)
+
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// fileLookup decrypts a profile's API key from its credentials file, or returns "" if there is none
+func fileLookup(profile string) (string, error) {
+	path, err := getCredentialsPath(profile)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := checkPrivateFile(path); err != nil {
+		return "", err
+	}
+
+	var creds credentialsFile
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if creds.Version != credentialsFileVersion {
+		return "", fmt.Errorf("%s: unsupported credentials version %d", path, creds.Version)
+	}
+	gcm, err := newCredentialsCipher(creds.Salt)
+	if err != nil {
+		return "", err
+	}
+	key, err := gcm.Open(nil, creds.Nonce, creds.Ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s cannot be decrypted on this machine; run 'ptn auth login' again", path)
+	}
+	return string(key), nil
+}
+
+// storeAPIKey saves a profile's API key in the keyring when available, otherwise in the
+// encrypted credentials file, and returns the backend used
+func storeAPIKey(profile string, key string, forceFile bool) (string, error) {
+	if !forceFile && keyringAvailable() {
+		err := keyringStore(profile, key)
+		if err == nil {
+			// Remove any older copy so the two backends never disagree
+			if path, err := getCredentialsPath(profile); err == nil {
+				os.Remove(path)
+			}
+			return backendKeyring, nil
+		}
+		fmt.Fprintln(os.Stderr, pkg.YellowBold("Warning: ")+"saving to the keyring failed, using the credentials file instead: "+err.Error())
+	}
+
+	if err := fileStore(profile, key); err != nil {
+		return "", err
+	}
+	if keyringAvailable() {
+		keyringDelete(profile)
+	}
+	return backendFile, nil
+}
+
+// loadAPIKey returns a profile's stored API key and its backend, preferring the keyring
+func loadAPIKey(profile string) (string, string, error) {
+	if keyringAvailable() {
+		if key, err := keyringLookup(profile); err == nil && key != "" {
+			return key, backendKeyring, nil
+		}
+	}
+
+	key, err := fileLookup(profile)
+	if err != nil || key == "" {
+		return "", "", err
+	}
+	return key, backendFile, nil
+}
+
+// deleteAPIKey removes a profile's API key from every backend and returns the backends it was removed from
+func deleteAPIKey(profile string) ([]string, error) {
+	var removed []string
+
+	if keyringAvailable() {
+		if key, err := keyringLookup(profile); err == nil && key != "" {
+			if err := keyringDelete(profile); err != nil {
+				return removed, err
+			}
+			removed = append(removed, backendKeyring)
+		}
+	}
+
+	path, err := getCredentialsPath(profile)
+	if err != nil {
+		return removed, err
+	}
+	if err := os.Remove(path); err == nil {
+		removed = append(removed, backendFile)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return removed, err
+	}
+
+	return removed, nil
+}
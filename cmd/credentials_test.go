@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreLookup(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		key     string
+	}{
+		{"default profile", defaultProfile, "sk-or-default-1234"},
+		{"named profile", "work", "sk-or-work-5678"},
+		{"key with symbols", "ci", "sk-or-v1-ab+/=_-9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupConfigEnv(t, "", "")
+			if err := fileStore(tt.profile, tt.key); err != nil {
+				t.Fatal(err)
+			}
+
+			path, err := getCredentialsPath(tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := info.Mode().Perm(); perm != 0600 {
+				t.Errorf("credentials file mode = %04o, want 0600", perm)
+			}
+			data, _ := os.ReadFile(path)
+			if strings.Contains(string(data), tt.key) {
+				t.Error("credentials file holds the key in plain text")
+			}
+
+			got, err := fileLookup(tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.key {
+				t.Errorf("fileLookup() = %q, want %q", got, tt.key)
+			}
+		})
+	}
+}
+
+func TestFileLookupErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(t *testing.T, path string)
+		wantErr string
+	}{
+		{
+			name:   "no file",
+			change: func(t *testing.T, path string) { os.Remove(path) },
+		},
+		{
+			name:    "readable by others",
+			change:  func(t *testing.T, path string) { os.Chmod(path, 0644) },
+			wantErr: "accessible by other users",
+		},
+		{
+			name: "tampered ciphertext",
+			change: func(t *testing.T, path string) {
+				editCredentials(t, path, func(creds *credentialsFile) { creds.Ciphertext[0] ^= 0xff })
+			},
+			wantErr: "cannot be decrypted",
+		},
+		{
+			name: "other salt",
+			change: func(t *testing.T, path string) {
+				editCredentials(t, path, func(creds *credentialsFile) { creds.Salt[0] ^= 0xff })
+			},
+			wantErr: "cannot be decrypted",
+		},
+		{
+			name: "unsupported version",
+			change: func(t *testing.T, path string) {
+				editCredentials(t, path, func(creds *credentialsFile) { creds.Version = credentialsFileVersion + 1 })
+			},
+			wantErr: "unsupported credentials version",
+		},
+		{
+			name:    "not JSON",
+			change:  func(t *testing.T, path string) { os.WriteFile(path, []byte("sk-or-plain"), 0600) },
+			wantErr: "invalid character",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupConfigEnv(t, "", "")
+			if err := fileStore(defaultProfile, "sk-or-1234"); err != nil {
+				t.Fatal(err)
+			}
+			path, err := getCredentialsPath(defaultProfile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(t, path)
+
+			got, err := fileLookup(defaultProfile)
+			if tt.wantErr == "" {
+				if err != nil || got != "" {
+					t.Errorf("fileLookup() = %q, %v, want no key and no error", got, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("fileLookup() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// editCredentials rewrites a credentials file after applying change to it
+func editCredentials(t *testing.T, path string, change func(*credentialsFile)) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var creds credentialsFile
+	if err := json.Unmarshal(data, &creds); err != nil {
+		t.Fatal(err)
+	}
+	change(&creds)
+	if data, err = json.Marshal(creds); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckPrivateFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		perm    os.FileMode
+		wantErr bool
+	}{
+		{"owner read and write", 0600, false},
+		{"owner read only", 0400, false},
+		{"group readable", 0640, true},
+		{"world readable", 0604, true},
+		{"group writable", 0620, true},
+		{"everyone", 0666, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte("{}"), tt.perm); err != nil {
+				t.Fatal(err)
+			}
+			// WriteFile is subject to the umask, so set the mode explicitly
+			if err := os.Chmod(path, tt.perm); err != nil {
+				t.Fatal(err)
+			}
+			if err := checkPrivateFile(path); (err != nil) != tt.wantErr {
+				t.Errorf("checkPrivateFile() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := checkPrivateFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("checkPrivateFile() of a missing file returned no error")
+	}
+}
+
+func TestStoreAPIKeyFile(t *testing.T) {
+	setupConfigEnv(t, "", "")
+	backend, err := storeAPIKey("work", "sk-or-1234", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend != backendFile {
+		t.Errorf("storeAPIKey() backend = %q, want %q", backend, backendFile)
+	}
+
+	key, backend, err := loadAPIKey("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "sk-or-1234" || backend != backendFile {
+		t.Errorf("loadAPIKey() = %q, %q, want the stored key from %q", key, backend, backendFile)
+	}
+	if key, _, err := loadAPIKey(defaultProfile); err != nil || key != "" {
+		t.Errorf("loadAPIKey() of another profile = %q, %v, want nothing", key, err)
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// Outcomes of a doctor check
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// doctorCheck is the result of one diagnostic, with a hint on how to fix a problem
+type doctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+	Hint   string
+}
+
+var doctorOffline bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose setup problems",
+	Long:  "Check the config, API key, network, the models your profiles and route rules use, and the terminal, and print a pass/fail report with hints for fixing problems. Use --offline to skip checks that need the network.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(pkg.CyanBold(pkg.Icon("🩺") + "Photon doctor:"))
+
+		failed := false
+		report := func(title string, checks []doctorCheck) {
+			fmt.Println()
+			fmt.Println(pkg.YellowBold(title))
+			for _, check := range checks {
+				printDoctorCheck(check)
+				failed = failed || check.Status == checkFail
+			}
+		}
+
+		report("Version", []doctorCheck{checkVersion()})
+		report("Config", append(checkConfigFiles(), checkConfigPermissions()...))
+
+		config, err := LoadConfig()
+		if err != nil {
+			// Everything below depends on the config, which was reported above
+			os.Exit(1)
+		}
+
+		report("Authentication", []doctorCheck{checkKeyPresent(config)})
+		report("Terminal", checkTerminal())
+
+		if doctorOffline {
+			fmt.Println()
+			fmt.Println(pkg.Muted("Skipped network, key validity and model checks (--offline)"))
+		} else {
+			client := checkHTTPClient(config)
+			network := checkNetwork(config)
+			report("Network", []doctorCheck{client, network})
+			if client.Status != checkFail && network.Status != checkFail && config.OpenRouterKey != "" {
+				validity := checkKeyValid(config)
+				report("API key", []doctorCheck{validity})
+				// Every probe would fail the same way with a rejected key
+				if validity.Status != checkFail {
+					report("Models", checkModels(config))
+				}
+			}
+		}
+
+		fmt.Println()
+		if failed {
+			fmt.Println(pkg.RedBold("Some checks failed; see the hints above"))
+			os.Exit(1)
+		}
+		fmt.Println(pkg.GreenBold(pkg.Icon("✅") + "Everything looks good"))
+	},
+}
+
+// checkVersion reports the photon version and platform
+func checkVersion() doctorCheck {
+	return doctorCheck{
+		Name:   "photon",
+		Status: checkPass,
+		Detail: fmt.Sprintf("%s (%s, %s/%s)", pkg.GetVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH),
+	}
+}
+
+// checkConfigFiles validates the user and project config files and the merged settings
+func checkConfigFiles() []doctorCheck {
+	var checks []doctorCheck
+
+	configPath, err := getConfigPath()
+	if err != nil {
+		return append(checks, doctorCheck{Name: "config file", Status: checkFail, Detail: err.Error(), Hint: "Make sure $HOME is set"})
+	}
+
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		checks = append(checks, doctorCheck{Name: "config file", Status: checkPass, Detail: "not created yet, using defaults"})
+	} else if _, warnings, err := readUserConfig(configPath); err != nil {
+		checks = append(checks, doctorCheck{Name: "config file", Status: checkFail, Detail: err.Error(), Hint: "Fix it with 'ptn config edit'"})
+	} else {
+		checks = append(checks, doctorCheck{Name: "config file", Status: checkPass, Detail: configPath})
+		for _, warning := range warnings {
+			checks = append(checks, doctorCheck{Name: "config file", Status: checkWarn, Detail: warning, Hint: "Remove or rename the key with 'ptn config edit'"})
+		}
+	}
+
+	if path := findProjectConfig(); path != "" {
+		if _, warnings, err := readProjectConfig(path); err != nil {
+			checks = append(checks, doctorCheck{Name: "project config", Status: checkFail, Detail: err.Error(), Hint: "Fix or remove " + path})
+		} else {
+			checks = append(checks, doctorCheck{Name: "project config", Status: checkPass, Detail: path})
+			for _, warning := range warnings {
+				checks = append(checks, doctorCheck{Name: "project config", Status: checkWarn, Detail: warning, Hint: "Remove or rename the key in " + path})
+			}
+		}
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return append(checks, doctorCheck{Name: "settings", Status: checkFail, Detail: err.Error(), Hint: "Fix the config files above"})
+	}
+	if err := config.ValidateSettings(); err != nil {
+		return append(checks, doctorCheck{Name: "settings", Status: checkFail, Detail: err.Error(), Hint: "Change the value with 'ptn config set' or 'ptn config unset'"})
+	}
+	return append(checks, doctorCheck{Name: "settings", Status: checkPass, Detail: "profile " + config.GetProfile() + ", model " + config.GetCurrentModel()})
+}
+
+// checkConfigPermissions makes sure the config directory and files holding keys are private
+func checkConfigPermissions() []doctorCheck {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil
+	}
+	dir := filepath.Dir(configPath)
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return []doctorCheck{{Name: "permissions", Status: checkWarn, Detail: fmt.Sprintf("%s is accessible by other users (mode %04o)", dir, perm), Hint: "Run 'chmod 700 " + dir + "'"}}
+	}
+
+	var checks []doctorCheck
+	paths, _ := filepath.Glob(filepath.Join(dir, "credentials*"))
+	if user, _, err := readUserConfig(configPath); err == nil {
+		for _, profile := range user.Profiles {
+			if profile.OpenRouterKey != "" {
+				paths = append(paths, configPath)
+				break
+			}
+		}
+	}
+	for _, path := range paths {
+		if err := checkPrivateFile(path); err != nil {
+			checks = append(checks, doctorCheck{Name: "permissions", Status: checkFail, Detail: err.Error(), Hint: "Run 'chmod 600 " + path + "'"})
+		}
+	}
+
+	if len(checks) == 0 {
+		checks = append(checks, doctorCheck{Name: "permissions", Status: checkPass, Detail: dir + " is private"})
+	}
+	return checks
+}
+
+// checkKeyPresent reports whether an API key is configured and where it comes from
+func checkKeyPresent(config *Config) doctorCheck {
+	if config.OpenRouterKey == "" {
+		if config.credentialsErr != nil {
+			return doctorCheck{Name: "api key", Status: checkFail, Detail: config.credentialsErr.Error(), Hint: "Fix the file permissions or run 'ptn auth login'"}
+		}
+		return doctorCheck{Name: "api key", Status: checkFail, Detail: "not set", Hint: "Run 'ptn auth login' or set PHOTON_OPEN_ROUTER_KEY"}
+	}
+
+	detail := maskSecret(config.OpenRouterKey) + " from " + config.describeLayer("openrouter_key")
+	if config.Origin("openrouter_key") == originUser {
+		return doctorCheck{Name: "api key", Status: checkWarn, Detail: detail + " in plain text", Hint: "Run 'ptn auth login' to move it to secure storage"}
+	}
+	return doctorCheck{Name: "api key", Status: checkPass, Detail: detail}
+}
+
+// checkHTTPClient makes sure the proxy, CA bundle and client certificate settings load
+func checkHTTPClient(config *Config) doctorCheck {
+	if _, err := pkg.NewHTTPClient(config.HTTPConfig()); err != nil {
+		return doctorCheck{Name: "connection", Status: checkFail, Detail: err.Error(), Hint: "Fix proxy, ca_file, client_cert or client_key with 'ptn config set'"}
+	}
+
+	httpConfig := config.HTTPConfig()
+	detail := "system CA roots"
+	if httpConfig.CAFile != "" {
+		detail = "system CA roots and " + httpConfig.CAFile
+	}
+	if httpConfig.CertFile != "" {
+		detail += ", client certificate " + httpConfig.CertFile
+	}
+	return doctorCheck{Name: "connection", Status: checkPass, Detail: detail + ", " + pkg.UserAgent()}
+}
+
+// checkNetwork connects to the API host, or to the proxy that requests to it would use
+func checkNetwork(config *Config) doctorCheck {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = pkg.DefaultBaseURL
+	}
+
+	target, err := url.Parse(baseURL)
+	if err != nil {
+		return doctorCheck{Name: "reachability", Status: checkFail, Detail: err.Error(), Hint: "Fix base_url with 'ptn config set base_url'"}
+	}
+
+	route := "direct"
+	dialURL := target
+	proxy, err := config.HTTPConfig().ProxyFor(target)
+	if err != nil {
+		return doctorCheck{Name: "reachability", Status: checkFail, Detail: "invalid proxy setting: " + err.Error(), Hint: "Fix the proxy setting, HTTPS_PROXY or HTTP_PROXY"}
+	}
+	if proxy != nil {
+		route = "via proxy " + proxy.Host
+		dialURL = proxy
+	}
+
+	address := dialURL.Host
+	if dialURL.Port() == "" {
+		port := "443"
+		if dialURL.Scheme == "http" {
+			port = "80"
+		}
+		address = net.JoinHostPort(dialURL.Hostname(), port)
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, config.HTTPConfig().ConnectTimeout+config.GetTimeout())
+	if err != nil {
+		return doctorCheck{Name: "reachability", Status: checkFail, Detail: fmt.Sprintf("cannot reach %s (%s): %s", target.Host, route, err), Hint: "Check your connection, HTTPS_PROXY/NO_PROXY and base_url"}
+	}
+	conn.Close()
+
+	return doctorCheck{Name: "reachability", Status: checkPass, Detail: fmt.Sprintf("%s %s in %dms", target.Host, route, time.Since(start).Milliseconds())}
+}
+
+// checkKeyValid asks the provider whether the API key is accepted
+func checkKeyValid(config *Config) doctorCheck {
+	client, err := config.NewClient()
+	if err != nil {
+		return doctorCheck{Name: "validity", Status: checkFail, Detail: "could not check: " + err.Error(), Hint: "Fix the settings reported above"}
+	}
+
+	info, err := client.CheckKey(context.Background())
+	var statusErr *pkg.StatusError
+	if errors.As(err, &statusErr) {
+		return doctorCheck{Name: "validity", Status: checkFail, Detail: "rejected: " + err.Error(), Hint: "Create a new key at https://openrouter.ai/keys and run 'ptn auth login'"}
+	}
+	if err != nil {
+		return doctorCheck{Name: "validity", Status: checkFail, Detail: "could not check: " + err.Error(), Hint: "Check base_url, proxy and ca_file with 'ptn config list'"}
+	}
+
+	detail := fmt.Sprintf("accepted, $%.2f used", info.Usage)
+	if info.Label != "" {
+		detail = info.Label + " " + detail
+	}
+	if info.Limit != nil {
+		detail += fmt.Sprintf(" of $%.2f", *info.Limit)
+		if info.Usage >= *info.Limit {
+			return doctorCheck{Name: "validity", Status: checkFail, Detail: detail, Hint: "Raise the key's credit limit on OpenRouter"}
+		}
+	}
+	return doctorCheck{Name: "validity", Status: checkPass, Detail: detail}
+}
+
+// checkModels probes every model a profile may send to through the active profile's connection
+func checkModels(config *Config) []doctorCheck {
+	modelIDs := doctorModels(config)
+
+	client, err := config.NewClient()
+	if err != nil {
+		return []doctorCheck{{Name: "models", Status: checkFail, Detail: "could not check: " + err.Error(), Hint: "Fix the settings reported above"}}
+	}
+
+	var checks []doctorCheck
+	for _, result := range client.PingAll(context.Background(), modelIDs) {
+		if result.Available {
+			checks = append(checks, doctorCheck{Name: result.ModelID, Status: checkPass, Detail: fmt.Sprintf("available in %dms", result.Latency.Milliseconds())})
+		} else {
+			checks = append(checks, doctorCheck{Name: result.ModelID, Status: checkFail, Detail: result.Err.Error(), Hint: "Try again later or pick another model with 'ptn model set'"})
+		}
+	}
+	return checks
+}
+
+// doctorModels returns the models of every profile, their route rules and classifiers, starting
+// with the current model. When any of them is auto, it may pick any model, so all are returned.
+func doctorModels(config *Config) []string {
+	modelIDs := []string{config.GetCurrentModel()}
+	add := func(layer *Config) {
+		for _, id := range append([]string{layer.CurrentModel, layer.AutoClassifier}, routeRuleModels(layer.RouteRules)...) {
+			if id != "" && !slices.Contains(modelIDs, id) {
+				modelIDs = append(modelIDs, id)
+			}
+		}
+	}
+	add(config)
+	for _, name := range config.ProfileNames() {
+		if profile, err := config.Profile(name); err == nil {
+			add(profile)
+		}
+	}
+
+	if !slices.Contains(modelIDs, pkg.AutoModel) {
+		return modelIDs
+	}
+	all := slices.Clone(pkg.GetModelOrder())
+	for _, id := range modelIDs {
+		if id != pkg.AutoModel && !slices.Contains(all, id) {
+			all = append(all, id)
+		}
+	}
+	return all
+}
+
+// routeRuleModels returns the models route rules name
+func routeRuleModels(rules []pkg.RouteRule) []string {
+	var modelIDs []string
+	for _, rule := range rules {
+		modelIDs = append(modelIDs, rule.Model)
+	}
+	return modelIDs
+}
+
+// checkTerminal reports whether stdout is a terminal and what it can display
+func checkTerminal() []doctorCheck {
+	var checks []doctorCheck
+
+	if term.IsTerminal(os.Stdout.Fd()) {
+		checks = append(checks, doctorCheck{Name: "tty", Status: checkPass, Detail: "stdout is a terminal, TERM=" + os.Getenv("TERM")})
+	} else {
+		checks = append(checks, doctorCheck{Name: "tty", Status: checkWarn, Detail: "stdout is not a terminal", Hint: "Use -o markdown, plain or json when piping output"})
+	}
+
+	if width := pkg.TerminalWidth(); width < 40 {
+		checks = append(checks, doctorCheck{Name: "width", Status: checkWarn, Detail: fmt.Sprintf("%d columns", width), Hint: "Widen the terminal to at least 40 columns for readable results"})
+	} else {
+		checks = append(checks, doctorCheck{Name: "width", Status: checkPass, Detail: fmt.Sprintf("%d columns", width)})
+	}
+
+	color := pkg.ColorProfileName()
+	switch {
+	case noColor:
+		color += ", disabled by --no-color"
+	case os.Getenv("NO_COLOR") != "":
+		color += ", disabled by NO_COLOR"
+	}
+	checks = append(checks, doctorCheck{Name: "color", Status: checkPass, Detail: color})
+
+	emoji := "enabled"
+	if !pkg.EmojiEnabled() {
+		emoji = "disabled"
+	}
+	checks = append(checks, doctorCheck{Name: "emoji", Status: checkPass, Detail: emoji})
+
+	return checks
+}
+
+// printDoctorCheck prints one check result, followed by its hint when it did not pass
+func printDoctorCheck(check doctorCheck) {
+	var status string
+	switch check.Status {
+	case checkPass:
+		status = pkg.GreenBold("PASS")
+	case checkWarn:
+		status = pkg.YellowBold("WARN")
+	default:
+		status = pkg.RedBold("FAIL")
+	}
+
+	fmt.Printf("  %s  %s %s\n", status, pkg.White(fmt.Sprintf("%-14s", check.Name)), check.Detail)
+	if check.Status != checkPass && check.Hint != "" {
+		fmt.Printf("        %s\n", pkg.Muted("→ "+check.Hint))
+	}
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorOffline, "offline", false, "Skip checks that need the network")
+}
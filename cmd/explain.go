@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// Environment variables the shell hook passes to ptn, and no other program
+const (
+	lastCommandEnv = "PHOTON_LAST_COMMAND"
+	lastStatusEnv  = "PHOTON_LAST_STATUS"
+)
+
+// maxExplainInput caps how much error output is sent; the end of a log is kept, since
+// that is where errors usually are
+const maxExplainInput = 16 * 1024
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [error text]",
+	Short: "Explain an error message or the last failed command",
+	Long: `Explain what an error means, with its likely causes and fixes.
+
+The error can be given as arguments or piped in, and with the shell hook installed a
+bare 'ptn explain' explains the last failed command and its exit code:
+  make 2>&1 | ptn explain
+  ptn explain "fatal: refusing to merge unrelated histories"
+  eval "$(ptn explain hook bash)"   # in ~/.bashrc; see 'ptn explain hook --help'
+
+API keys, tokens, passwords, email addresses and home directory user names are redacted before
+anything is sent, unless --no-redact is given.`,
+	Args:              cobra.ArbitraryArgs,
+	ValidArgsFunction: cobra.NoFileCompletions,
+	Run: func(cmd *cobra.Command, args []string) {
+		input, err := explainInput(args)
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		config, client := mustResearchClient()
+		// The explain template asks for causes and fixes; an explicit --template still wins
+		if flagOverrides["template"] == "" {
+			if client, err = client.With(pkg.WithTemplate("explain")); err != nil {
+				fmt.Println(pkg.RedBold("Error: ") + err.Error())
+				os.Exit(1)
+			}
+		}
+
+		runResearch(config, client, "explain", input)
+	},
+}
+
+var explainHookCmd = &cobra.Command{
+	Use:   "hook <bash|zsh|fish>",
+	Short: "Print the shell hook that lets 'ptn explain' see the last command",
+	Long: `Print a shell hook that records the last command and its exit code after every command,
+so a bare 'ptn explain' can explain it. Nothing is sent anywhere until you run 'ptn explain'.
+The hook keeps them in unexported shell variables and defines a ptn function that passes
+them only to ptn, so other programs never see your command lines.
+
+Add it to your shell's startup file:
+  bash:  eval "$(ptn explain hook bash)"    in ~/.bashrc
+  zsh:   eval "$(ptn explain hook zsh)"     in ~/.zshrc
+  fish:  ptn explain hook fish | source     in ~/.config/fish/config.fish
+
+The hook only sees the command line, not its output; pipe the output for a better answer:
+  make 2>&1 | ptn explain`,
+	Args:                  cobra.ExactArgs(1),
+	ValidArgs:             []string{"bash", "zsh", "fish"},
+	DisableFlagsInUseLine: true,
+	// The hook is evaluated by the shell, so skip the config and logging setup
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		hook, ok := shellHooks[args[0]]
+		if !ok {
+			fmt.Println(pkg.RedBold("Error: ") + fmt.Sprintf("unsupported shell '%s', expected bash, zsh or fish", args[0]))
+			os.Exit(1)
+		}
+		fmt.Print(hook)
+	},
+}
+
+func init() {
+	explainCmd.AddCommand(explainHookCmd)
+}
+
+// shellHooks record the last command and its exit code for 'ptn explain', by shell. The
+// values stay in shell variables and only the ptn function passes them on.
+var shellHooks = map[string]string{
+	"bash": `# photon: record the last command and its exit code for 'ptn explain'
+__ptn_record_last() {
+  local exit_code=$?
+  __ptn_last_status=$exit_code
+  __ptn_last_command="$(HISTTIMEFORMAT= builtin history 1 | sed 's/^ *[0-9]*[* ] *//')"
+  return $exit_code
+}
+if [[ ";${PROMPT_COMMAND:-};" != *";__ptn_record_last;"* ]]; then
+  PROMPT_COMMAND="__ptn_record_last${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+ptn() {
+  PHOTON_LAST_STATUS=$__ptn_last_status PHOTON_LAST_COMMAND=$__ptn_last_command command ptn "$@"
+}
+`,
+	"zsh": `# photon: record the last command and its exit code for 'ptn explain'
+__ptn_preexec() { __ptn_command=$1 }
+__ptn_precmd() {
+  local exit_code=$?
+  if [[ -n $__ptn_command ]]; then
+    __ptn_last_status=$exit_code __ptn_last_command=$__ptn_command
+    __ptn_command=
+  fi
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook preexec __ptn_preexec
+add-zsh-hook precmd __ptn_precmd
+ptn() {
+  PHOTON_LAST_STATUS=$__ptn_last_status PHOTON_LAST_COMMAND=$__ptn_last_command command ptn "$@"
+}
+`,
+	"fish": `# photon: record the last command and its exit code for 'ptn explain'
+function __ptn_record_last --on-event fish_postexec
+    set -l exit_code $status
+    set -g __ptn_last_status $exit_code
+    set -g __ptn_last_command $argv[1]
+end
+function ptn --wraps ptn
+    env PHOTON_LAST_STATUS="$__ptn_last_status" PHOTON_LAST_COMMAND="$__ptn_last_command" ptn $argv
+end
+`,
+}
+
+// explainInput collects the error to explain from the arguments, piped input or the shell
+// hook, in that order of preference
+func explainInput(args []string) (string, error) {
+	if len(args) > 0 {
+		return strings.Join(args, " "), nil
+	}
+
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("reading piped input: %w", err)
+		}
+		output := strings.TrimSpace(ansi.Strip(string(data)))
+		if output == "" {
+			return "", errors.New("the piped input was empty; to include error output, redirect it too: command 2>&1 | ptn explain")
+		}
+		return "Output:\n" + tailText(output, maxExplainInput), nil
+	}
+
+	command := strings.TrimSpace(os.Getenv(lastCommandEnv))
+	if command == "" {
+		return "", errors.New("nothing to explain: pass the error text, pipe it in (command 2>&1 | ptn explain), or install the shell hook described in 'ptn explain hook --help'")
+	}
+	status := os.Getenv(lastStatusEnv)
+	if status == "0" {
+		return "", fmt.Errorf("the last command succeeded: %s\nTo explain its output, pipe it in: command 2>&1 | ptn explain", command)
+	}
+	return fmt.Sprintf("Command: %s\nExit code: %s\n(The output of the command was not captured.)", command, status), nil
+}
+
+// tailText returns the last limit bytes of text, starting at a line boundary, and notes
+// when earlier lines were dropped
+func tailText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	text = text[len(text)-limit:]
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	return "(earlier output omitted)\n" + text
+}
+
+// plural formats a count with a noun, adding an s unless the count is one
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
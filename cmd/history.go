@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// historyEntry is one research result recorded in the history file
+type historyEntry struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Query    string    `json:"query"`
+	Model    string    `json:"model"`
+	Template string    `json:"template"`
+	// Source is where the query came from, such as "cli" or "serve"
+	Source string `json:"source"`
+	pkg.FormattedResponse
+}
+
+// getHistoryPath returns the history file next to the user config
+func getHistoryPath() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "history.jsonl"), nil
+}
+
+// newHistoryID returns a short random ID for a history entry
+func newHistoryID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// recordHistory appends a research result to the history file and returns its entry.
+// Each entry is one JSON line, appended under a lock so concurrent writers never interleave.
+func recordHistory(entry historyEntry) (historyEntry, error) {
+	entry.ID = newHistoryID()
+	entry.Time = time.Now().UTC()
+
+	dir, err := ensureConfigDir()
+	if err != nil {
+		return entry, err
+	}
+	path := filepath.Join(dir, "history.jsonl")
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return entry, err
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return entry, err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return entry, err
+	}
+	return entry, file.Close()
+}
+
+// readHistory returns recorded research results, newest first. Lines that cannot be
+// decoded, such as one cut short by a crash, are skipped.
+func readHistory() ([]historyEntry, error) {
+	path, err := getHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// answeringModel returns the model that answered: the one the auto model picked, if any
+func answeringModel(client *pkg.Client, result pkg.FormattedResponse) string {
+	if result.Route != nil {
+		return result.Route.Model
+	}
+	return client.Model()
+}
+
+// saveResearch records a successful research result when history is enabled and returns
+// its history ID, or "" if it was not recorded. Failures are logged rather than shown,
+// since history must never get in the way of an answer.
+func saveResearch(config *Config, client *pkg.Client, source string, query string, result pkg.FormattedResponse) string {
+	if config == nil || !config.HistoryEnabled() {
+		return ""
+	}
+	// History is kept on disk, so it holds the query and answer as the model saw them
+	redaction := client.Redact(query)
+	entry, err := recordHistory(historyEntry{
+		Query:             redaction.Text,
+		Model:             answeringModel(client, result),
+		Template:          client.Template(),
+		Source:            source,
+		FormattedResponse: redaction.RedactResponse(result),
+	})
+	if err != nil {
+		logger.Warn("recording history failed", "error", err)
+		return ""
+	}
+	return entry.ID
+}
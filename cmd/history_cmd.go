@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recorded research results",
+	Long: "List research results recorded in the history file, newest first. Secrets and personal data are recorded " +
+		"as the placeholders sent to the model, such as [EMAIL_1], unless redaction is off. " +
+		"Recording is off by default; turn it on with 'ptn config set history true'.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := readHistory()
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error reading history: ") + err.Error())
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Println(pkg.Muted("No history yet"))
+			return
+		}
+
+		if historyLimit > 0 && len(entries) > historyLimit {
+			entries = entries[:historyLimit]
+		}
+		width := pkg.TerminalWidth()
+		for _, entry := range entries {
+			line := fmt.Sprintf("%s  %s  %s  %s", pkg.YellowBold(entry.ID), pkg.Muted(entry.Time.Local().Format("2006-01-02 15:04")),
+				pkg.Cyan(fmt.Sprintf("%-16s", entry.Model)), strings.Join(strings.Fields(entry.Query), " "))
+			fmt.Println(ansi.Truncate(line, width, "…"))
+		}
+	},
+}
+
+var historyExportCmd = &cobra.Command{
+	Use:   "export <id>",
+	Short: "Export a recorded result as a Markdown note or HTML page",
+	Long: "Write a recorded research result to notes_dir as Markdown with YAML front matter, named by note_filename. " +
+		"Use --html for a standalone page, --append to add it to the daily note, or --out to pick the file. " +
+		"The ID may be shortened to any unique prefix.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeHistoryIDs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		entry, err := findHistoryEntry(args[0])
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+		note := pkg.Note{
+			ID:       entry.ID,
+			Time:     entry.Time,
+			Query:    entry.Query,
+			Model:    entry.Model,
+			Template: entry.Template,
+			Result:   entry.FormattedResponse,
+		}
+
+		if exportOut != "" {
+			if exportAppend {
+				fmt.Println(pkg.RedBold("Error: ") + "--out and --append cannot be combined")
+				os.Exit(1)
+			}
+			content := note.Markdown()
+			if exportHTML {
+				content = note.HTML()
+			}
+			if exportOut == "-" {
+				fmt.Print(content)
+				return
+			}
+			if err := os.WriteFile(exportOut, []byte(content), 0600); err != nil {
+				fmt.Println(pkg.RedBold("Error writing note: ") + err.Error())
+				os.Exit(1)
+			}
+			fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"Saved to ") + exportOut)
+			return
+		}
+
+		path, err := writeNote(config, note, exportHTML, exportAppend)
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error writing note: ") + err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"Saved to ") + path)
+	},
+}
+
+var (
+	historyLimit int
+	exportHTML   bool
+	exportAppend bool
+	exportOut    string
+)
+
+func init() {
+	historyCmd.AddCommand(historyExportCmd)
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Number of entries to list, or 0 for all")
+	historyExportCmd.Flags().BoolVar(&exportHTML, "html", false, "Write a standalone HTML page instead of Markdown")
+	historyExportCmd.Flags().BoolVar(&exportAppend, "append", false, "Append to the daily note instead of creating a new note")
+	historyExportCmd.Flags().StringVar(&exportOut, "out", "", "Write to this file instead of notes_dir, or - for stdout")
+}
+
+// findHistoryEntry returns the history entry with the given ID or unique ID prefix
+func findHistoryEntry(id string) (historyEntry, error) {
+	entries, err := readHistory()
+	if err != nil {
+		return historyEntry{}, err
+	}
+
+	var matches []historyEntry
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+		if strings.HasPrefix(entry.ID, id) {
+			matches = append(matches, entry)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return historyEntry{}, fmt.Errorf("no history entry '%s'; run 'ptn history' to list them", id)
+	case 1:
+		return matches[0], nil
+	}
+	return historyEntry{}, fmt.Errorf("'%s' matches %d history entries, give more of the ID", id, len(matches))
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// lockFile is a no-op where advisory file locks are unavailable; writes are still atomic
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+var (
+	debugLogging bool
+	logFile      string
+	// logger receives API and parser logs; it discards them unless logging is enabled
+	logger = slog.New(slog.DiscardHandler)
+)
+
+// logLevel returns the level requested by --debug or PHOTON_LOG, and false when logging is off
+func logLevel() (slog.Level, bool, error) {
+	if debugLogging {
+		return slog.LevelDebug, true, nil
+	}
+
+	value := strings.TrimSpace(os.Getenv("PHOTON_LOG"))
+	switch strings.ToLower(value) {
+	case "", "off", "none":
+		return 0, false, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, false, fmt.Errorf("invalid PHOTON_LOG level '%s' (use debug, info, warn or error)", value)
+	}
+	return level, true, nil
+}
+
+// setupLogging sends request, response and parser logs to stderr or a log file. The
+// full-screen UI would be garbled by log lines, so it logs to ~/.photon/logs instead.
+func setupLogging(cmd *cobra.Command, config *Config) {
+	level, enabled, err := logLevel()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, pkg.YellowBold("Warning: ")+err.Error()+", logging is off")
+		return
+	}
+	if !enabled {
+		return
+	}
+
+	path := logFile
+	if path == "" {
+		path = os.Getenv("PHOTON_LOG_FILE")
+	}
+	if path == "" && usesTUI(cmd, config) {
+		if path, err = defaultLogPath(); err != nil {
+			fmt.Fprintln(os.Stderr, pkg.YellowBold("Warning: ")+"cannot create log directory: "+err.Error()+", logging is off")
+			return
+		}
+	}
+
+	var w io.Writer = os.Stderr
+	if path != "" {
+		file, err := os.OpenFile(expandHome(path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, pkg.YellowBold("Warning: ")+"cannot open log file: "+err.Error()+", logging is off")
+			return
+		}
+		// The file stays open for the lifetime of the process
+		w = file
+		fmt.Fprintln(os.Stderr, pkg.Muted("Logging to "+path))
+	}
+
+	logger = slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+
+	if config != nil {
+		logger.Debug("config loaded", "profile", config.GetProfile(), "model", config.GetCurrentModel(),
+			"template", config.GetTemplate(), "base_url", config.BaseURL, "timeout", config.GetTimeout())
+	}
+}
+
+// usesTUI reports whether the command is about to draw the research UI
+func usesTUI(cmd *cobra.Command, config *Config) bool {
+	if cmd.HasParent() || dumpRaw {
+		return false
+	}
+	return interactive || config == nil || config.GetOutput() == pkg.OutputPretty
+}
+
+// defaultLogPath returns today's log file in the logs directory next to the user config
+func defaultLogPath() (string, error) {
+	dir, err := ensureConfigDir()
+	if err != nil {
+		return "", err
+	}
+	logsDir := filepath.Join(dir, "logs")
+	if err := os.MkdirAll(logsDir, 0700); err != nil {
+		return "", err
+	}
+	return filepath.Join(logsDir, "ptn-"+time.Now().Format("2006-01-02")+".log"), nil
+}
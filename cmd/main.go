@@ -1,7 +1,8 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
@@ -13,68 +14,181 @@ import (
 const (
 	stateLoading = iota
 	stateResult
+	stateInteractive
+	stateSelecting
 )
 
 type state int
 
-type fallbackMsg struct{}
+// errTimedOut is the result of an interactive query that got no answer within the timeout
+var errTimedOut = errors.New("no answer before the request timeout")
+
+type fallbackMsg struct {
+	requestID int
+}
 
 type llmResultMsg struct {
-	Research pkg.FormattedResponse
+	requestID int
+	Research  pkg.FormattedResponse
+	// err is why the query failed; Research then holds the error message
+	err error
+	// historyID is the history entry the result was recorded as, if any
+	historyID string
 }
 
 type model struct {
 	spinner      spinner.Model
 	loadingState state
-	question     string
-	fallback     bool
-	result       pkg.FormattedResponse
+	// question is the question result answers; asking and query are the question in flight
+	// and the prompt sent for it, which holds the last exchange for follow-ups
+	question    string
+	asking      string
+	query       string
+	config      *Config
+	client      *pkg.Client
+	source      string
+	interactive bool
+	requestID   int
+	fallback    bool
+	result      pkg.FormattedResponse
+	resultErr   error
+	historyID   string
+	viewer      pkg.ResultViewModel
+	selector    pkg.ModelSelectorModel
+	width       int
+	height      int
 }
 
-func initialModel(question string) model {
+func initialModel(question string, config *Config, client *pkg.Client, source string, interactive bool) model {
 	return model{
 		spinner:      pkg.CreateSpinner(),
 		loadingState: stateLoading,
 		question:     question,
+		asking:       question,
+		query:        question,
+		config:       config,
+		client:       client,
+		source:       source,
+		interactive:  interactive,
 		fallback:     false,
+		width:        pkg.TerminalWidth(),
+		height:       24,
 	}
 }
 
 func (m model) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
-		timeoutCmd(),
-		getLLMResearchCmd(m.question),
+		timeoutCmd(m.requestID, m.client.Timeout()),
+		getLLMResearchCmd(m.requestID, m.query, m.config, m.client, m.source),
+	)
+}
+
+// startRequest puts the model back into the loading state and fires a new query
+func (m model) startRequest() (model, tea.Cmd) {
+	m.requestID++
+	m.loadingState = stateLoading
+	m.fallback = false
+	return m, tea.Batch(
+		m.spinner.Tick,
+		timeoutCmd(m.requestID, m.client.Timeout()),
+		getLLMResearchCmd(m.requestID, m.query, m.config, m.client, m.source),
 	)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			return m, tea.Quit
+		}
 	case spinner.TickMsg:
+		if m.loadingState != stateLoading {
+			return m, nil
+		}
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
 	case fallbackMsg:
-		if m.loadingState == stateLoading {
+		if m.loadingState == stateLoading && msg.requestID == m.requestID {
 			m.fallback = true
-			return m, tea.Quit
+			if !m.interactive {
+				return m, tea.Quit
+			}
+			// Stay in the session: keep the last answer, or show the timeout if there is none yet
+			if m.requestID == 0 {
+				m.resultErr = errTimedOut
+				m.result = pkg.ErrorResponse(errTimedOut)
+				m.viewer = pkg.NewResultView(m.result, m.width, m.height)
+			}
+			m.loadingState = stateInteractive
+			m.viewer = m.viewer.TimedOut()
 		}
 		return m, nil
 	case llmResultMsg:
-		if m.loadingState == stateLoading && !m.fallback {
-			m.result = msg.Research
-			m.loadingState = stateResult
-			return m, tea.Quit
+		if m.loadingState == stateLoading && !m.fallback && msg.requestID == m.requestID {
+			m.result, m.resultErr, m.historyID = msg.Research, msg.err, msg.historyID
+			m.question = m.asking
+			if !m.interactive {
+				m.loadingState = stateResult
+				return m, tea.Quit
+			}
+			m.loadingState = stateInteractive
+			m.viewer = pkg.NewResultView(m.result, m.width, m.height)
 		}
 		return m, nil
+	case pkg.ReaskMsg:
+		m.loadingState = stateSelecting
+		selector, _ := pkg.NewEmbeddedModelSelector(m.client.Model()).WithClient(m.client).Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+		m.selector = selector.(pkg.ModelSelectorModel)
+		return m, nil
+	case pkg.ModelSelectedMsg:
+		if msg.ModelID == "" {
+			m.loadingState = stateInteractive
+			return m, nil
+		}
+		client, err := m.client.With(pkg.WithModel(msg.ModelID))
+		if err != nil {
+			m.loadingState = stateInteractive
+			return m, nil
+		}
+		m.client = client
+		return m.startRequest()
+	case pkg.SaveMsg:
+		m.viewer = m.viewer.Saved(writeNote(m.config, newNote(m.client, m.historyID, m.question, m.result), false, false))
+		return m, nil
+	case pkg.FollowUpMsg:
+		m.asking = msg.Question
+		m.query = msg.Question
+		if m.resultErr == nil {
+			m.query = pkg.BuildFollowUpQuery(m.question, m.result, msg.Question)
+		}
+		return m.startRequest()
 	}
-	return m, nil
+
+	var cmd tea.Cmd
+	switch m.loadingState {
+	case stateInteractive:
+		m.viewer, cmd = m.viewer.Update(msg)
+	case stateSelecting:
+		var selector tea.Model
+		selector, cmd = m.selector.Update(msg)
+		m.selector = selector.(pkg.ModelSelectorModel)
+	}
+	return m, cmd
 }
 
 func (m model) View() string {
 	switch m.loadingState {
 	case stateResult:
-		return pkg.RenderResultView(m.result)
+		return pkg.RenderResultView(m.result, m.width)
+	case stateInteractive:
+		return m.viewer.View()
+	case stateSelecting:
+		return m.selector.View()
 	default:
 		uiModel := pkg.UIModel{
 			Spinner:  m.spinner,
@@ -85,26 +199,25 @@ func (m model) View() string {
 	}
 }
 
-func timeoutCmd() tea.Cmd {
+// timeoutCmd shows the fallback once the timeout passes, or never when it is 0
+func timeoutCmd(requestID int, timeout time.Duration) tea.Cmd {
+	if timeout <= 0 {
+		return nil
+	}
 	return func() tea.Msg {
-		time.Sleep(15 * time.Second)
-		return fallbackMsg{}
+		time.Sleep(timeout)
+		return fallbackMsg{requestID: requestID}
 	}
 }
 
-func getLLMResearchCmd(question string) tea.Cmd {
+func getLLMResearchCmd(requestID int, question string, config *Config, client *pkg.Client, source string) tea.Cmd {
 	return func() tea.Msg {
-		// Load config to get current model
-		config, err := LoadConfig()
+		research, err := client.Research(context.Background(), question)
 		if err != nil {
-			return llmResultMsg{Research: pkg.FormattedResponse{
-				Summary: fmt.Sprintf("Error loading config: %s", err.Error()),
-			}}
+			return llmResultMsg{requestID: requestID, Research: pkg.ErrorResponse(err), err: err}
 		}
-		
-		// Use the configured model
-		research := pkg.FormatWithModel(question, config.GetCurrentModel())
-		return llmResultMsg{Research: research}
+		historyID := saveResearch(config, client, source, question, research)
+		return llmResultMsg{requestID: requestID, Research: research, historyID: historyID}
 	}
 }
 
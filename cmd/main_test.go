@@ -0,0 +1,217 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// newTestModel returns a TUI model whose client is never called, since commands are not run
+func newTestModel(t *testing.T, interactive bool) model {
+	t.Helper()
+	client, err := pkg.NewClient(pkg.WithAPIKey("sk-test"), pkg.WithBaseURL("http://127.0.0.1:0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return initialModel("What is Go?", &Config{}, client, "query", interactive)
+}
+
+// answer returns a result message for the given request with a recognizable summary
+func answer(requestID int, summary string) llmResultMsg {
+	return llmResultMsg{requestID: requestID, Research: pkg.FormattedResponse{Summary: summary, KeyPoints: []string{summary + " point"}}}
+}
+
+// update feeds messages to the model in order, returning it and the last command
+func update(m model, msgs ...tea.Msg) (model, tea.Cmd) {
+	var cmd tea.Cmd
+	for _, msg := range msgs {
+		var next tea.Model
+		next, cmd = m.Update(msg)
+		m = next.(model)
+	}
+	return m, cmd
+}
+
+func isQuit(cmd tea.Cmd) bool {
+	if cmd == nil {
+		return false
+	}
+	_, ok := cmd().(tea.QuitMsg)
+	return ok
+}
+
+func TestModelUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		interactive  bool
+		msgs         []tea.Msg
+		wantState    state
+		wantQuit     bool
+		wantFallback bool
+		wantSummary  string
+		wantQuestion string
+		wantErr      bool
+	}{
+		{
+			name:         "result quits outside interactive mode",
+			msgs:         []tea.Msg{answer(0, "Go is a language")},
+			wantState:    stateResult,
+			wantQuit:     true,
+			wantSummary:  "Go is a language",
+			wantQuestion: "What is Go?",
+		},
+		{
+			name:         "timeout quits outside interactive mode",
+			msgs:         []tea.Msg{fallbackMsg{requestID: 0}},
+			wantState:    stateLoading,
+			wantQuit:     true,
+			wantFallback: true,
+			wantQuestion: "What is Go?",
+		},
+		{
+			name:         "result opens the result view in interactive mode",
+			interactive:  true,
+			msgs:         []tea.Msg{answer(0, "Go is a language")},
+			wantState:    stateInteractive,
+			wantSummary:  "Go is a language",
+			wantQuestion: "What is Go?",
+		},
+		{
+			name:         "timeout before any answer stays interactive with an error",
+			interactive:  true,
+			msgs:         []tea.Msg{fallbackMsg{requestID: 0}},
+			wantState:    stateInteractive,
+			wantFallback: true,
+			wantSummary:  pkg.ErrorResponse(errTimedOut).Summary,
+			wantQuestion: "What is Go?",
+			wantErr:      true,
+		},
+		{
+			name:        "follow-up timeout keeps the last answer",
+			interactive: true,
+			msgs: []tea.Msg{
+				answer(0, "Go is a language"),
+				pkg.FollowUpMsg{Question: "Who made it?"},
+				fallbackMsg{requestID: 1},
+			},
+			wantState:    stateInteractive,
+			wantFallback: true,
+			wantSummary:  "Go is a language",
+			wantQuestion: "What is Go?",
+		},
+		{
+			name:        "follow-up answer replaces the question",
+			interactive: true,
+			msgs: []tea.Msg{
+				answer(0, "Go is a language"),
+				pkg.FollowUpMsg{Question: "Who made it?"},
+				answer(1, "Google made it"),
+			},
+			wantState:    stateInteractive,
+			wantSummary:  "Google made it",
+			wantQuestion: "Who made it?",
+		},
+		{
+			name:        "stale result is ignored",
+			interactive: true,
+			msgs: []tea.Msg{
+				answer(0, "Go is a language"),
+				pkg.FollowUpMsg{Question: "Who made it?"},
+				answer(0, "Late answer"),
+			},
+			wantState:    stateLoading,
+			wantSummary:  "Go is a language",
+			wantQuestion: "What is Go?",
+		},
+		{
+			name:         "timeout of an earlier request is ignored",
+			msgs:         []tea.Msg{fallbackMsg{requestID: 3}},
+			wantState:    stateLoading,
+			wantQuestion: "What is Go?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, cmd := update(newTestModel(t, tt.interactive), tt.msgs...)
+			if m.loadingState != tt.wantState {
+				t.Errorf("state = %d, want %d", m.loadingState, tt.wantState)
+			}
+			if got := isQuit(cmd); got != tt.wantQuit {
+				t.Errorf("quit = %v, want %v", got, tt.wantQuit)
+			}
+			if m.fallback != tt.wantFallback {
+				t.Errorf("fallback = %v, want %v", m.fallback, tt.wantFallback)
+			}
+			if m.result.Summary != tt.wantSummary {
+				t.Errorf("summary = %q, want %q", m.result.Summary, tt.wantSummary)
+			}
+			if m.question != tt.wantQuestion {
+				t.Errorf("question = %q, want %q", m.question, tt.wantQuestion)
+			}
+			if (m.resultErr != nil) != tt.wantErr {
+				t.Errorf("resultErr = %v, want error %v", m.resultErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestModelFollowUpQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		msgs    []tea.Msg
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "first follow-up holds the first exchange",
+			msgs: []tea.Msg{
+				answer(0, "Go is a language"),
+				pkg.FollowUpMsg{Question: "Who made it?"},
+			},
+			want: []string{"Previous question: What is Go?", "Go is a language", "Follow-up question: Who made it?"},
+		},
+		{
+			name: "second follow-up holds only the last exchange",
+			msgs: []tea.Msg{
+				answer(0, "Go is a language"),
+				pkg.FollowUpMsg{Question: "Who made it?"},
+				answer(1, "Google made it"),
+				pkg.FollowUpMsg{Question: "When?"},
+			},
+			want:    []string{"Previous question: Who made it?", "Google made it", "Follow-up question: When?"},
+			notWant: []string{"What is Go?", "Go is a language"},
+		},
+		{
+			name: "follow-up after a timeout is asked on its own",
+			msgs: []tea.Msg{
+				fallbackMsg{requestID: 0},
+				pkg.FollowUpMsg{Question: "Who made Go?"},
+			},
+			want:    []string{"Who made Go?"},
+			notWant: []string{"Previous question"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := update(newTestModel(t, true), tt.msgs...)
+			if m.loadingState != stateLoading {
+				t.Fatalf("state = %d, want a new request", m.loadingState)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(m.query, want) {
+					t.Errorf("query %q does not contain %q", m.query, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(m.query, notWant) {
+					t.Errorf("query %q contains %q", m.query, notWant)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// mcpProtocolVersions are the MCP revisions this server speaks, newest first
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// maxCompareModels bounds how many models one compare_models call may query
+const maxCompareModels = 5
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run an MCP server on stdin and stdout",
+	Long: `Run a Model Context Protocol server over stdio so agents can call photon as a tool.
+
+Tools:
+  research        research a query, optionally with a model and template
+  list_models     list the available models
+  compare_models  research one query with several models side by side
+
+Add it to an MCP client with the command "ptn mcp".`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Stdout carries the protocol, so every problem is reported on stderr
+		config, err := LoadConfig()
+		if err == nil {
+			err = config.Validate()
+		}
+		var client *pkg.Client
+		if err == nil {
+			client, err = config.NewClient()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, pkg.RedBold("Error: ")+err.Error())
+			os.Exit(1)
+		}
+
+		server := newMCPServer(config, client, os.Stdout)
+		if err := server.serve(context.Background(), os.Stdin); err != nil {
+			fmt.Fprintln(os.Stderr, pkg.RedBold("Error: ")+err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+// rpcMessage is an incoming JSON-RPC request or notification
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is an outgoing JSON-RPC response
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error member of a JSON-RPC response
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpServer answers MCP requests read line by line from an input stream
+type mcpServer struct {
+	config *Config
+	client *pkg.Client
+
+	writeMu sync.Mutex
+	out     *json.Encoder
+
+	// calls holds the cancel function of each running tool call by request ID
+	callsMu sync.Mutex
+	calls   map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// newMCPServer creates a server that writes its responses to out
+func newMCPServer(config *Config, client *pkg.Client, out io.Writer) *mcpServer {
+	return &mcpServer{
+		config: config,
+		client: client,
+		out:    json.NewEncoder(out),
+		calls:  make(map[string]context.CancelFunc),
+	}
+}
+
+// serve handles messages until the input ends, then waits for running tool calls.
+// Tool calls run concurrently so a slow model does not block pings or cancellation.
+func (s *mcpServer) serve(ctx context.Context, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg rpcMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			s.reply(nil, nil, &rpcError{Code: rpcParseError, Message: "parse error: " + err.Error()})
+			continue
+		}
+		if msg.JSONRPC != "2.0" {
+			if msg.ID != nil {
+				s.reply(msg.ID, nil, &rpcError{Code: rpcInvalidRequest, Message: "invalid request: jsonrpc must be \"2.0\""})
+			}
+			continue
+		}
+		if msg.Method == "" {
+			// Responses to server requests; this server never sends any
+			continue
+		}
+		if msg.ID == nil {
+			s.notify(msg)
+			continue
+		}
+
+		if msg.Method == "tools/call" {
+			callCtx, cancel := context.WithCancel(ctx)
+			key := string(msg.ID)
+			s.callsMu.Lock()
+			s.calls[key] = cancel
+			s.callsMu.Unlock()
+
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				result, rpcErr := s.callTool(callCtx, msg.Params)
+				cancelled := callCtx.Err() != nil
+
+				s.callsMu.Lock()
+				delete(s.calls, key)
+				s.callsMu.Unlock()
+				cancel()
+
+				// A cancelled request must not be answered
+				if !cancelled {
+					s.reply(msg.ID, result, rpcErr)
+				}
+			}()
+			continue
+		}
+
+		result, rpcErr := s.handle(msg)
+		s.reply(msg.ID, result, rpcErr)
+	}
+
+	s.wg.Wait()
+	return scanner.Err()
+}
+
+// handle answers requests other than tool calls
+func (s *mcpServer) handle(msg rpcMessage) (interface{}, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(msg.Params, &params)
+
+		// Agree to the client's revision when it is one we speak, otherwise offer our newest
+		version := mcpProtocolVersions[0]
+		if slices.Contains(mcpProtocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "photon", "version": pkg.GetVersion()},
+			"instructions":    "Use research for structured answers with a summary and key points. Pick a model from list_models when the user asks for one.",
+		}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		return map[string]interface{}{"tools": mcpTools()}, nil
+	}
+
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+// notify handles notifications, which are never answered
+func (s *mcpServer) notify(msg rpcMessage) {
+	if msg.Method != "notifications/cancelled" {
+		return
+	}
+
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if json.Unmarshal(msg.Params, &params) != nil {
+		return
+	}
+	s.callsMu.Lock()
+	cancel := s.calls[string(params.RequestID)]
+	s.callsMu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// reply writes a response; concurrent tool calls share the output stream
+func (s *mcpServer) reply(id json.RawMessage, result interface{}, rpcErr *rpcError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.out.Encode(rpcResponse{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr})
+}
+
+// mcpTools describes the tools this server offers
+func mcpTools() []map[string]interface{} {
+	modelProperty := map[string]interface{}{
+		"type":        "string",
+		"description": "Model ID from list_models; defaults to the configured model",
+		"enum":        pkg.GetModelOrder(),
+	}
+	templateProperty := map[string]interface{}{
+		"type":        "string",
+		"description": "Prompt template; defaults to the configured template",
+		"enum":        pkg.GetTemplateNames(),
+	}
+	queryProperty := map[string]interface{}{
+		"type":        "string",
+		"description": "The question to research",
+	}
+
+	return []map[string]interface{}{
+		{
+			"name":        "research",
+			"title":       "Research",
+			"description": "Research a question and return a short summary with key points",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query":    queryProperty,
+					"model":    modelProperty,
+					"template": templateProperty,
+				},
+				"required": []string{"query"},
+			},
+		},
+		{
+			"name":        "list_models",
+			"title":       "List models",
+			"description": "List the models photon can research with, including what each is best for",
+			"inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+			"annotations": map[string]interface{}{"readOnlyHint": true},
+		},
+		{
+			"name":        "compare_models",
+			"title":       "Compare models",
+			"description": fmt.Sprintf("Research one question with 2 to %d models and return each answer", maxCompareModels),
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": queryProperty,
+					"models": map[string]interface{}{
+						"type":     "array",
+						"items":    map[string]interface{}{"type": "string", "enum": pkg.GetModelOrder()},
+						"minItems": 2,
+						"maxItems": maxCompareModels,
+					},
+					"template": templateProperty,
+				},
+				"required": []string{"query", "models"},
+			},
+		},
+	}
+}
+
+// compareResult is one model's answer in a compare_models result
+type compareResult struct {
+	Model string `json:"model"`
+	Error string `json:"error,omitempty"`
+	*pkg.FormattedResponse
+}
+
+// callTool runs a tool. Invalid arguments are protocol errors; failed research is
+// reported in the tool result so the agent can see and react to it.
+func (s *mcpServer) callTool(ctx context.Context, params json.RawMessage) (interface{}, *rpcError) {
+	var call struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	if call.Arguments == nil {
+		call.Arguments = json.RawMessage("{}")
+	}
+
+	switch call.Name {
+	case "research":
+		var args researchRequest
+		if err := json.Unmarshal(call.Arguments, &args); err != nil || args.Query == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "research needs a query string"}
+		}
+		client, err := s.client.With(pkg.WithModel(args.Model), pkg.WithTemplate(args.Template))
+		if err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+
+		result, err := client.Research(ctx, args.Query)
+		if err != nil {
+			return toolError(err), nil
+		}
+		return toolResult(newResearchResult(s.config, client, "mcp", args.Query, result)), nil
+
+	case "list_models":
+		return toolResult(map[string]interface{}{"models": describeModels(s.client.Model())}), nil
+
+	case "compare_models":
+		var args struct {
+			Query    string   `json:"query"`
+			Models   []string `json:"models"`
+			Template string   `json:"template"`
+		}
+		if err := json.Unmarshal(call.Arguments, &args); err != nil || args.Query == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "compare_models needs a query string and a list of models"}
+		}
+		if len(args.Models) < 2 || len(args.Models) > maxCompareModels {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("compare_models needs 2 to %d models", maxCompareModels)}
+		}
+
+		clients := make([]*pkg.Client, len(args.Models))
+		for i, id := range args.Models {
+			client, err := s.client.With(pkg.WithModel(id), pkg.WithTemplate(args.Template))
+			if err != nil {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			}
+			clients[i] = client
+		}
+
+		results := make([]compareResult, len(clients))
+		var wg sync.WaitGroup
+		for i, client := range clients {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = compareResult{Model: client.Model()}
+				result, err := client.Research(ctx, args.Query)
+				if err != nil {
+					results[i].Error = err.Error()
+					return
+				}
+				saved := newResearchResult(s.config, client, "mcp", args.Query, result)
+				results[i].FormattedResponse = &saved.FormattedResponse
+			}()
+		}
+		wg.Wait()
+
+		return toolResult(map[string]interface{}{"query": args.Query, "results": results}), nil
+	}
+
+	return nil, &rpcError{Code: rpcInvalidParams, Message: "unknown tool: " + call.Name}
+}
+
+// toolResult returns structured tool output, with the same JSON as text for older clients
+func toolResult(structured interface{}) map[string]interface{} {
+	text, _ := json.MarshalIndent(structured, "", "  ")
+	return map[string]interface{}{
+		"content":           []map[string]string{{"type": "text", "text": string(text)}},
+		"structuredContent": structured,
+	}
+}
+
+// toolError reports a failed tool call to the agent
+func toolError(err error) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": err.Error()}},
+		"isError": true,
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// fakeAPI is a chat completions endpoint that answers with the API name of the model asked.
+// Requests for the blocking model wait until they are cancelled, after signalling started.
+type fakeAPI struct {
+	*httptest.Server
+	blocking string
+	started  chan struct{}
+}
+
+// newFakeAPI starts a fake API and a client that sends to it, with history in a temporary home
+func newFakeAPI(t *testing.T) (*fakeAPI, *pkg.Client) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	api := &fakeAPI{started: make(chan struct{}, 1)}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload.Model == api.blocking {
+			api.started <- struct{}{}
+			<-r.Context().Done()
+			return
+		}
+
+		content := fmt.Sprintf("Summary:\nAnswer from %s\n\nKey Points:\n1. First point\n2. Second point", payload.Model)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+			"usage":   map[string]int{"prompt_tokens": 10, "completion_tokens": 20, "total_tokens": 30},
+		})
+	}))
+	t.Cleanup(api.Close)
+
+	client, err := pkg.NewClient(pkg.WithAPIKey("sk-test"), pkg.WithBaseURL(api.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api, client
+}
+
+// apiName returns the name a model is sent to the API as
+func apiName(t *testing.T, id string) string {
+	t.Helper()
+	model, err := pkg.GetModel(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.APIName
+}
+
+// mcpReply is a decoded JSON-RPC response
+type mcpReply struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// runMCP serves the input lines and returns the responses by ID
+func runMCP(t *testing.T, client *pkg.Client, in io.Reader) map[string]mcpReply {
+	t.Helper()
+	var out bytes.Buffer
+	if err := newMCPServer(&Config{}, client, &out).serve(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+
+	replies := make(map[string]mcpReply)
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var reply mcpReply
+		if err := decoder.Decode(&reply); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		replies[string(reply.ID)] = reply
+	}
+	return replies
+}
+
+// mcpInput joins JSON-RPC messages into line-delimited input
+func mcpInput(lines ...string) io.Reader {
+	return strings.NewReader(strings.Join(lines, "\n") + "\n")
+}
+
+// toolOutput decodes the structured content of a tools/call result
+func toolOutput(t *testing.T, reply mcpReply, v interface{}) {
+	t.Helper()
+	if reply.Error != nil {
+		t.Fatalf("got error %d %s", reply.Error.Code, reply.Error.Message)
+	}
+	var result struct {
+		StructuredContent json.RawMessage `json:"structuredContent"`
+		IsError           bool            `json:"isError"`
+	}
+	if err := json.Unmarshal(reply.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError {
+		t.Fatalf("tool failed: %s", reply.Result)
+	}
+	if err := json.Unmarshal(result.StructuredContent, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMCPInitialize(t *testing.T) {
+	_, client := newFakeAPI(t)
+	replies := runMCP(t, client, mcpInput(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+	))
+
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"1", "2025-03-26"},
+		{"2", mcpProtocolVersions[0]},
+	}
+	for _, tt := range tests {
+		var result struct {
+			ProtocolVersion string                 `json:"protocolVersion"`
+			ServerInfo      map[string]string      `json:"serverInfo"`
+			Capabilities    map[string]interface{} `json:"capabilities"`
+		}
+		if err := json.Unmarshal(replies[tt.id].Result, &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.ProtocolVersion != tt.want {
+			t.Errorf("request %s: got protocol version %q, want %q", tt.id, result.ProtocolVersion, tt.want)
+		}
+		if result.ServerInfo["name"] != "photon" {
+			t.Errorf("request %s: got server name %q, want photon", tt.id, result.ServerInfo["name"])
+		}
+		if _, ok := result.Capabilities["tools"]; !ok {
+			t.Errorf("request %s: tools capability missing", tt.id)
+		}
+	}
+
+	if _, ok := replies["3"]; !ok {
+		t.Error("ping was not answered")
+	}
+	if len(replies) != 3 {
+		t.Errorf("got %d responses, want 3; notifications must not be answered", len(replies))
+	}
+}
+
+func TestMCPToolsList(t *testing.T) {
+	_, client := newFakeAPI(t)
+	replies := runMCP(t, client, mcpInput(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+
+	var result struct {
+		Tools []struct {
+			Name        string                 `json:"name"`
+			InputSchema map[string]interface{} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(replies["1"].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+		if tool.InputSchema["type"] != "object" {
+			t.Errorf("%s: input schema type is %v, want object", tool.Name, tool.InputSchema["type"])
+		}
+	}
+	if got := strings.Join(names, ","); got != "research,list_models,compare_models" {
+		t.Errorf("got tools %s", got)
+	}
+}
+
+func TestMCPResearch(t *testing.T) {
+	_, client := newFakeAPI(t)
+	replies := runMCP(t, client, mcpInput(
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"research","arguments":{"query":"what is go"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"research","arguments":{"query":"what is go","model":"kimi"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"research","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"research","arguments":{"query":"q","model":"nope"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"no_such_tool"}}`,
+	))
+
+	tests := []struct {
+		id    string
+		model string
+	}{
+		{"1", client.Model()},
+		{"2", "kimi"},
+	}
+	for _, tt := range tests {
+		var result researchResult
+		toolOutput(t, replies[tt.id], &result)
+		if result.Model != tt.model {
+			t.Errorf("request %s: got model %q, want %q", tt.id, result.Model, tt.model)
+		}
+		if want := "Answer from " + apiName(t, tt.model); result.Summary != want {
+			t.Errorf("request %s: got summary %q, want %q", tt.id, result.Summary, want)
+		}
+		if len(result.KeyPoints) != 2 {
+			t.Errorf("request %s: got %d key points, want 2", tt.id, len(result.KeyPoints))
+		}
+	}
+
+	for _, id := range []string{"3", "4", "5"} {
+		if reply := replies[id]; reply.Error == nil || reply.Error.Code != rpcInvalidParams {
+			t.Errorf("request %s: got %+v, want an invalid params error", id, reply.Error)
+		}
+	}
+}
+
+func TestMCPCompareModels(t *testing.T) {
+	_, client := newFakeAPI(t)
+	replies := runMCP(t, client, mcpInput(
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"compare_models","arguments":{"query":"what is go","models":["deepseek-r1","kimi"]}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"compare_models","arguments":{"query":"what is go","models":["kimi"]}}}`,
+	))
+
+	var result struct {
+		Query   string `json:"query"`
+		Results []struct {
+			Model   string `json:"model"`
+			Error   string `json:"error"`
+			Summary string `json:"summary"`
+		} `json:"results"`
+	}
+	toolOutput(t, replies["1"], &result)
+	if len(result.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(result.Results))
+	}
+	for i, id := range []string{"deepseek-r1", "kimi"} {
+		got := result.Results[i]
+		if got.Model != id || got.Error != "" || got.Summary != "Answer from "+apiName(t, id) {
+			t.Errorf("result %d: got %+v, want the answer of %s", i, got, id)
+		}
+	}
+
+	if reply := replies["2"]; reply.Error == nil || reply.Error.Code != rpcInvalidParams {
+		t.Errorf("one model: got %+v, want an invalid params error", reply.Error)
+	}
+}
+
+func TestMCPCancelled(t *testing.T) {
+	api, client := newFakeAPI(t)
+	api.blocking = apiName(t, "kimi")
+
+	in, writer := io.Pipe()
+	go func() {
+		fmt.Fprintln(writer, `{"jsonrpc":"2.0","id":"slow","method":"tools/call","params":{"name":"research","arguments":{"query":"q","model":"kimi"}}}`)
+		<-api.started
+		fmt.Fprintln(writer, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"slow","reason":"user cancelled"}}`)
+		fmt.Fprintln(writer, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+		writer.Close()
+	}()
+	replies := runMCP(t, client, in)
+
+	if _, ok := replies[`"slow"`]; ok {
+		t.Error("a cancelled request was answered")
+	}
+	if _, ok := replies["2"]; !ok {
+		t.Error("ping after the cancellation was not answered")
+	}
+}
+
+func TestMCPProtocolErrors(t *testing.T) {
+	_, client := newFakeAPI(t)
+	replies := runMCP(t, client, mcpInput(
+		`{not json`,
+		`{"jsonrpc":"1.0","id":2,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"resources/list"}`,
+	))
+
+	tests := []struct {
+		id   string
+		code int
+	}{
+		{"null", rpcParseError},
+		{"2", rpcInvalidRequest},
+		{"3", rpcMethodNotFound},
+	}
+	for _, tt := range tests {
+		reply, ok := replies[tt.id]
+		if !ok {
+			t.Errorf("no response with id %s", tt.id)
+			continue
+		}
+		if reply.Error == nil || reply.Error.Code != tt.code {
+			t.Errorf("id %s: got error %+v, want code %d", tt.id, reply.Error, tt.code)
+		}
+	}
+}
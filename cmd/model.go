@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -42,23 +44,29 @@ var modelCurrentCmd = &cobra.Command{
 		}
 
 		currentModelID := config.GetCurrentModel()
+		if currentModelID == pkg.AutoModel {
+			fmt.Println(pkg.CyanBold(pkg.Icon("🤖")+"Current Model: ") + pkg.YellowBold(pkg.AutoModel))
+			fmt.Println(pkg.Muted("A model is picked for each query; see 'ptn model route <query>'"))
+			return
+		}
 		model, err := pkg.GetModel(currentModelID)
 		if err != nil {
 			fmt.Println(pkg.RedBold("Error: ") + err.Error())
 			os.Exit(1)
 		}
 
-		fmt.Println(pkg.CyanBold("🤖 Current Model:"))
+		fmt.Println(pkg.CyanBold(pkg.Icon("🤖") + "Current Model:"))
 		fmt.Println()
 		fmt.Print(pkg.FormatModelInfo(*model))
 	},
 }
 
 var modelSetCmd = &cobra.Command{
-	Use:   "set [model-id]",
-	Short: "Set the current model",
-	Long:  "Set the AI model to use for research queries. If no model-id is provided, an interactive selection menu will be shown.",
-	Args:  cobra.MaximumNArgs(1),
+	Use:               "set [model-id]",
+	Short:             "Set the current model",
+	Long:              "Set the AI model to use for research queries, or auto to pick one for each query. If no model-id is provided, an interactive selection menu will be shown.",
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeModelChoices,
 	Run: func(cmd *cobra.Command, args []string) {
 		config, err := LoadConfig()
 		if err != nil {
@@ -69,8 +77,9 @@ var modelSetCmd = &cobra.Command{
 		var modelID string
 
 		if len(args) == 0 {
-			// Interactive mode
-			modelID = selectModelInteractively(config.GetCurrentModel())
+			// Interactive mode; models can only be tested once a key is configured
+			client, _ := config.NewClient()
+			modelID = selectModelInteractively(config.GetCurrentModel(), client)
 		} else {
 			// Direct mode
 			modelID = args[0]
@@ -82,10 +91,14 @@ var modelSetCmd = &cobra.Command{
 		}
 
 		// Validate model
-		model, err := pkg.GetModel(modelID)
-		if err != nil {
-			fmt.Println(pkg.RedBold("Error: ") + err.Error())
-			os.Exit(1)
+		name := modelID
+		if modelID != pkg.AutoModel {
+			model, err := pkg.GetModel(modelID)
+			if err != nil {
+				fmt.Println(pkg.RedBold("Error: ") + err.Error())
+				os.Exit(1)
+			}
+			name = model.Name
 		}
 
 		// Set the model
@@ -95,16 +108,20 @@ var modelSetCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		fmt.Println(pkg.GreenBold("✅ Model set to: ") + pkg.YellowBold(model.Name))
-		fmt.Println(pkg.Cyan("Next queries will use this model"))
+		fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"Model set to: ") + pkg.YellowBold(name))
+		if origin := config.Origin("current_model"); origin == originUser || origin == originDefault {
+			fmt.Println(pkg.Cyan("Next queries will use this model"))
+		}
+		warnIfOverridden(config, "current_model")
 	},
 }
 
 var modelInfoCmd = &cobra.Command{
-	Use:   "info <model-id>",
-	Short: "Show model details",
-	Long:  "Display detailed information about a specific AI model",
-	Args:  cobra.ExactArgs(1),
+	Use:               "info <model-id>",
+	Short:             "Show model details",
+	Long:              "Display detailed information about a specific AI model",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeModelIDs,
 	Run: func(cmd *cobra.Command, args []string) {
 		modelID := args[0]
 		model, err := pkg.GetModel(modelID)
@@ -136,13 +153,106 @@ var modelResetCmd = &cobra.Command{
 		}
 
 		model, _ := pkg.GetModel(defaultModel)
-		fmt.Println(pkg.GreenBold("✅ Model reset to default: ") + pkg.YellowBold(model.Name))
+		fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"Model reset to default: ") + pkg.YellowBold(model.Name))
+	},
+}
+
+var modelRouteCmd = &cobra.Command{
+	Use:   "route <query>",
+	Short: "Show which model auto would pick for a query",
+	Long: `Show which model the auto model would pick for a query and why, without sending anything.
+
+Queries are sorted into coding, reasoning, bilingual, image, quick and general, and each
+category goes to the model best suited to it. Change the routing under "route_rules" in the
+config file; rules are checked in order and the first matching pattern wins:
+  "route_rules": [
+    {"category": "coding", "pattern": "(?i)terraform|helm"},
+    {"category": "reasoning", "model": "kimi"}
+  ]
+A rule with a pattern routes matching queries to its model, or to its category's model;
+a rule without a pattern changes the model of its category. Set auto_classifier to a model
+to have it classify queries instead of the built-in heuristics.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: cobra.NoFileCompletions,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := LoadConfig()
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error loading config: ") + err.Error())
+			os.Exit(1)
+		}
+		if err := config.ValidateSettings(); err != nil {
+			fmt.Println(pkg.RedBold("Configuration error: ") + err.Error())
+			os.Exit(1)
+		}
+		router, _ := pkg.NewRouter(config.AutoClassifier, config.RouteRules...)
+		redactor, _ := config.NewRedactor()
+
+		query := redactor.Redact(strings.Join(args, " ")).Text
+		route := router.Route(query, config.GetLanguage())
+		model, _ := pkg.GetModel(route.Model)
+		fmt.Printf("%s %s %s\n", pkg.YellowBold(model.Name), pkg.Muted("("+route.Model+")"), pkg.Cyan(route.Category))
+		fmt.Println(pkg.Muted(route.Reason))
+		if router.Classifier() != "" {
+			fmt.Println(pkg.Muted(fmt.Sprintf("Queries that match no rule are classified by %s when sent; this preview uses the heuristics.", router.Classifier())))
+		}
+	},
+}
+
+var pingAll bool
+
+var modelPingCmd = &cobra.Command{
+	Use:               "ping [model-id]",
+	Short:             "Test model availability",
+	Long:              "Send a tiny probe request to a model, or to every model with --all, and report availability and latency",
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeModelIDs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := LoadConfig()
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error loading config: ") + err.Error())
+			os.Exit(1)
+		}
+
+		var modelIDs []string
+
+		switch {
+		case pingAll:
+			modelIDs = pkg.GetModelOrder()
+		case len(args) == 1:
+			if !pkg.ValidateModel(args[0]) {
+				fmt.Println(pkg.RedBold("Error: ") + fmt.Sprintf("model '%s' not found", args[0]))
+				os.Exit(1)
+			}
+			modelIDs = args
+		case config.GetCurrentModel() == pkg.AutoModel:
+			// The auto model may send a query to any of them
+			modelIDs = pkg.GetModelOrder()
+		default:
+			modelIDs = []string{config.GetCurrentModel()}
+		}
+
+		client, err := config.NewClient()
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(pkg.Cyan(fmt.Sprintf("Probing %d model(s)...", len(modelIDs))))
+		fmt.Println()
+		results := client.PingAll(context.Background(), modelIDs)
+		fmt.Print(pkg.FormatPingTable(results))
+
+		for _, result := range results {
+			if !result.Available {
+				os.Exit(1)
+			}
+		}
 	},
 }
 
 // selectModelInteractively shows an interactive toggle-based model selection
-func selectModelInteractively(currentModel string) string {
-	selectedModel, err := pkg.RunModelSelector(currentModel)
+func selectModelInteractively(currentModel string, client *pkg.Client) string {
+	selectedModel, err := pkg.RunModelSelector(currentModel, client)
 	if err != nil {
 		fmt.Println(pkg.RedBold("Error running model selector: ") + err.Error())
 		return ""
@@ -157,4 +267,8 @@ func init() {
 	modelCmd.AddCommand(modelSetCmd)
 	modelCmd.AddCommand(modelInfoCmd)
 	modelCmd.AddCommand(modelResetCmd)
+	modelCmd.AddCommand(modelPingCmd)
+	modelCmd.AddCommand(modelRouteCmd)
+
+	modelPingCmd.Flags().BoolVarP(&pingAll, "all", "a", false, "Probe every available model concurrently")
 }
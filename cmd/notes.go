@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// errAppendHTML is returned when --append and --html are combined; daily notes are Markdown
+var errAppendHTML = errors.New("--append adds to a Markdown daily note and cannot be combined with --html")
+
+// writeNote writes a note to the notes directory and returns its path. With appendDaily the
+// note becomes a section of today's daily note, which is created if needed; otherwise a new
+// file is created, numbered when the name is already taken.
+func writeNote(config *Config, note pkg.Note, asHTML bool, appendDaily bool) (string, error) {
+	if asHTML && appendDaily {
+		return "", errAppendHTML
+	}
+
+	pattern, ext, content := config.GetNoteFilename(), ".md", note.Markdown()
+	if appendDaily {
+		pattern, content = config.GetDailyNote(), note.Entry()
+	} else if asHTML {
+		ext, content = ".html", note.HTML()
+	}
+
+	name, err := note.Filename(pattern, ext)
+	if err != nil {
+		return "", err
+	}
+	path, err := filepath.Abs(filepath.Join(config.GetNotesDir(), name))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+
+	if appendDaily {
+		return path, appendToNote(path, content)
+	}
+	return createNote(path, content)
+}
+
+// newNote returns a note of an answer the client gave, recorded in history as historyID
+func newNote(client *pkg.Client, historyID string, question string, result pkg.FormattedResponse) pkg.Note {
+	return pkg.Note{
+		ID:       historyID,
+		Time:     time.Now(),
+		Query:    question,
+		Model:    answeringModel(client, result),
+		Template: client.Template(),
+		Result:   result,
+	}
+}
+
+// createNote writes content to a new file at path, or at path-2, path-3 and so on if taken
+func createNote(path string, content string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	for n := 1; n <= 100; n++ {
+		candidate := path
+		if n > 1 {
+			candidate = base + "-" + strconv.Itoa(n) + ext
+		}
+		file, err := os.OpenFile(candidate, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.WriteString(content); err != nil {
+			file.Close()
+			return "", err
+		}
+		return candidate, file.Close()
+	}
+	return "", fmt.Errorf("too many notes named like %s", filepath.Base(path))
+}
+
+// appendToNote adds content to the end of a note, separated from what is already there by a blank line
+func appendToNote(path string, content string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(existing) > 0 {
+		content = "\n" + content
+		if existing[len(existing)-1] != '\n' {
+			content = "\n" + content
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
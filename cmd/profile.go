@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage config profiles",
+	Long:  "Manage named profiles, each with its own API key, base URL, model, template and other settings. Select one for a single command with --profile or PHOTON_PROFILE.",
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Long:  "List all profiles with their model, base URL and whether an API key is stored",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		names := config.ProfileNames()
+		if len(names) == 0 {
+			names = []string{defaultProfile}
+		}
+
+		for _, name := range names {
+			profile, err := config.Profile(name)
+			if err != nil {
+				profile = &Config{}
+			}
+
+			marker := "  "
+			label := pkg.White(fmt.Sprintf("%-16s", name))
+			if name == config.GetProfile() {
+				marker = pkg.GreenBold("* ")
+				label = pkg.GreenBold(fmt.Sprintf("%-16s", name))
+			}
+
+			model := profile.CurrentModel
+			if model == "" {
+				model = pkg.GetDefaultModel()
+			}
+			baseURL := profile.BaseURL
+			if baseURL == "" {
+				baseURL = pkg.DefaultBaseURL
+			}
+			key := "no key"
+			if stored, _, _ := loadAPIKey(name); stored != "" || profile.OpenRouterKey != "" {
+				key = "key stored"
+			}
+
+			fmt.Printf("%s%s %s %s %s\n", marker, label, pkg.Cyan(fmt.Sprintf("%-20s", model)), pkg.Muted(baseURL), pkg.Muted("("+key+")"))
+		}
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:               "use <name>",
+	Short:             "Switch the active profile",
+	Long:              "Make a profile the default for future commands",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProfiles,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		if err := config.UseProfile(args[0]); err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"Now using profile: ") + pkg.YellowBold(args[0]))
+		if env := os.Getenv("PHOTON_PROFILE"); env != "" && env != args[0] {
+			fmt.Println(pkg.YellowBold("Note: ") + "PHOTON_PROFILE=" + env + " overrides this in the current shell")
+		}
+	},
+}
+
+var profileFrom string
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a profile",
+	Long:  "Create an empty profile, or copy the settings of another profile with --from. API keys are never copied.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		if err := config.CreateProfile(args[0], profileFrom); err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"Created profile: ") + pkg.YellowBold(args[0]))
+		fmt.Println(pkg.Cyan(fmt.Sprintf("Store its key with 'ptn --profile %s auth login' and change settings with 'ptn --profile %s config set'", args[0], args[0])))
+	},
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:               "delete <name>",
+	Short:             "Delete a profile",
+	Long:              "Delete a profile and its stored API key",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProfiles,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+
+		if err := config.DeleteProfile(args[0]); err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(pkg.GreenBold(pkg.Icon("✅")+"Deleted profile: ") + pkg.YellowBold(args[0]))
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+
+	profileCreateCmd.Flags().StringVar(&profileFrom, "from", "", "Copy settings from an existing profile")
+	profileCreateCmd.RegisterFlagCompletionFunc("from", completeProfileFlag)
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
 	"github.com/spf13/cobra"
 
 	"github.com/Jacky040124/photon/pkg"
@@ -15,37 +18,233 @@ var rootCmd = &cobra.Command{
 	Short: "Packets of pure knowledge at light speed",
 	Long:  "Photon is a lightning-fast terminal research tool that delivers packets of pure knowledge at light speed.",
 	Args:  cobra.ExactArgs(1),
+	// The query is free text, so only subcommands are completed
+	ValidArgsFunction: cobra.NoFileCompletions,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		collectFlagOverrides(cmd)
+		// Commands report config errors themselves; setup falls back to defaults
+		config, _ := LoadConfig()
+		setupOutput(config)
+		setupLogging(cmd, config)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		// Load and validate config
-		config, err := LoadConfig()
-		if err != nil {
-			fmt.Println(pkg.RedBold("Error loading config: ") + err.Error())
+		if htmlNote && appendNote {
+			fmt.Println(pkg.RedBold("Error: ") + errAppendHTML.Error())
 			os.Exit(1)
 		}
-		
-		err = config.Validate()
+
+		config, client := mustResearchClient()
+		runResearch(config, client, "cli", args[0])
+	},
+}
+
+// mustResearchClient loads and validates the config and returns it with an API client,
+// exiting with a hint on how to store a key if none is configured
+func mustResearchClient() (*Config, *pkg.Client) {
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Println(pkg.RedBold("Error loading config: ") + err.Error())
+		os.Exit(1)
+	}
+
+	err = config.Validate()
+	if err != nil {
+		fmt.Println(pkg.RedBold("Configuration error: ") + err.Error())
+		fmt.Println("Run 'ptn auth login' to store your OpenRouter API key,")
+		fmt.Println("or set it for this shell: export PHOTON_OPEN_ROUTER_KEY=\"your-api-key\"")
+		os.Exit(1)
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		fmt.Println(pkg.RedBold("Error: ") + err.Error())
+		os.Exit(1)
+	}
+	return config, client
+}
+
+// runResearch answers a question and shows the result in the configured output format,
+// recording it in history under source
+func runResearch(config *Config, client *pkg.Client, source string, question string) {
+	if redaction := client.Redact(question); len(redaction.Values) > 0 {
+		fmt.Fprintln(os.Stderr, pkg.Muted(fmt.Sprintf("Redacted %s (%s) before sending; use --no-redact to send them as is",
+			plural(len(redaction.Values), "value"), strings.Join(redaction.Rules(), ", "))))
+	}
+	if warning := client.ContextWarning(question); warning != "" {
+		fmt.Fprintln(os.Stderr, pkg.YellowBold("Warning: ")+warning)
+	}
+
+	// Print the model output exactly as received, skipping the parser and the UI
+	if dumpRaw {
+		content, err := client.Complete(context.Background(), question)
 		if err != nil {
-			fmt.Println(pkg.RedBold("Configuration error: ") + err.Error())
-			fmt.Println("Please set PHOTON_OPEN_ROUTER_KEY environment variable")
-			fmt.Println("Example: export PHOTON_OPEN_ROUTER_KEY=\"your-api-key\"")
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
 			os.Exit(1)
 		}
+		fmt.Println(content)
+		return
+	}
 
-		question := args[0]
-		m := initialModel(question)
-
-		_, err = tea.NewProgram(m).Run()
+	// Non-interactive formats skip the TUI so output can be piped
+	if format := config.GetOutput(); format != pkg.OutputPretty && !interactive {
+		result, researchErr := client.Research(context.Background(), question)
+		historyID := ""
+		if researchErr != nil {
+			result = pkg.ErrorResponse(researchErr)
+		} else {
+			historyID = saveResearch(config, client, source, question, result)
+		}
+		output, err := pkg.FormatResult(format, question, answeringModel(client, result), result, pkg.TerminalWidth())
 		if err != nil {
-			fmt.Println(pkg.RedBold("could not run program: ") + err.Error())
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
 			os.Exit(1)
 		}
-	},
+		fmt.Print(output)
+		// Plain output has no room for it, so say which model auto picked on stderr
+		if route := pkg.FormatRoute(result.Route); route != "" && format == pkg.OutputPlain {
+			fmt.Fprintln(os.Stderr, pkg.Muted(route))
+		}
+		if researchErr == nil {
+			saveRequestedNote(config, client, historyID, question, result)
+		}
+		return
+	}
+
+	m := initialModel(question, config, client, source, interactive)
+
+	var programOpts []tea.ProgramOption
+	if interactive {
+		programOpts = append(programOpts, tea.WithAltScreen())
+	}
+	// Piped input is the question, not key presses
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		programOpts = append(programOpts, tea.WithInput(nil))
+	}
+
+	finalModel, err := tea.NewProgram(m, programOpts...).Run()
+	if err != nil {
+		fmt.Println(pkg.RedBold("could not run program: ") + err.Error())
+		os.Exit(1)
+	}
+
+	final, ok := finalModel.(model)
+	if !ok {
+		return
+	}
+	// The alternate screen is cleared on exit, so leave the last answer in the scrollback
+	if final.loadingState == stateInteractive {
+		pkg.PrintFormattedResearch(final.viewer.Result())
+	}
+	if (final.loadingState == stateResult || final.loadingState == stateInteractive) && final.resultErr == nil {
+		saveRequestedNote(config, final.client, final.historyID, final.question, final.result)
+	}
 }
 
-func Execute() {
-	// Add model subcommand
+// saveRequestedNote writes an answer to the notes directory when --save, --append or --html
+// asked for it. Messages go to stderr so piped output stays clean.
+func saveRequestedNote(config *Config, client *pkg.Client, historyID string, question string, result pkg.FormattedResponse) {
+	if !saveNote && !appendNote && !htmlNote {
+		return
+	}
+
+	path, err := writeNote(config, newNote(client, historyID, question, result), htmlNote, appendNote)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, pkg.RedBold("Error saving note: ")+err.Error())
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, pkg.GreenBold(pkg.Icon("✅")+"Saved to ")+path)
+}
+
+var (
+	interactive bool
+	noColor     bool
+	noEmoji     bool
+	noRedact    bool
+	dumpRaw     bool
+	saveNote    bool
+	appendNote  bool
+	htmlNote    bool
+)
+
+func init() {
+	rootCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Keep the result open to scroll, copy, save or ask follow-ups")
+	rootCmd.Flags().BoolVar(&dumpRaw, "dump-raw", false, "Print the unparsed model output instead of the formatted answer")
+	rootCmd.Flags().BoolVar(&saveNote, "save", false, "Save the answer as a Markdown note in notes_dir")
+	rootCmd.Flags().BoolVar(&appendNote, "append", false, "Append the answer to today's daily note in notes_dir")
+	rootCmd.Flags().BoolVar(&htmlNote, "html", false, "Save the answer as a standalone HTML page in notes_dir")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also respects NO_COLOR)")
+	rootCmd.PersistentFlags().BoolVar(&noEmoji, "no-emoji", false, "Disable emoji in output")
+	rootCmd.PersistentFlags().BoolVar(&noRedact, "no-redact", false, "Send queries as is, without replacing secrets and personal data with placeholders")
+	rootCmd.PersistentFlags().StringP("model", "m", "", "Model to use, overriding config files and PHOTON_MODEL")
+	rootCmd.PersistentFlags().StringP("template", "t", "", "Prompt template to use, overriding config files and PHOTON_TEMPLATE")
+	rootCmd.PersistentFlags().String("theme", "", "Color theme to use, overriding config files and PHOTON_THEME")
+	rootCmd.PersistentFlags().String("timeout", "", "Maximum time to wait for an answer, e.g. 30s, or 0 for no limit")
+	rootCmd.PersistentFlags().StringP("output", "o", "", "Output format: "+strings.Join(pkg.GetOutputFormats(), ", "))
+	rootCmd.PersistentFlags().String("lang", "", "Language of answers and messages, overriding config files and PHOTON_LANG: "+strings.Join(pkg.GetLanguages(), ", ")+" or auto")
+	rootCmd.PersistentFlags().BoolVar(&debugLogging, "debug", false, "Log requests, responses and parser decisions (same as PHOTON_LOG=debug)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Write logs to this file instead of stderr (also PHOTON_LOG_FILE)")
+	rootCmd.PersistentFlags().StringVar(&profileOverride, "profile", "", "Config profile to use, overriding PHOTON_PROFILE and 'ptn profile use'")
+
+	rootCmd.RegisterFlagCompletionFunc("model", completeModelFlag)
+	rootCmd.RegisterFlagCompletionFunc("template", completeTemplateFlag)
+	rootCmd.RegisterFlagCompletionFunc("theme", completeThemeFlag)
+	rootCmd.RegisterFlagCompletionFunc("output", completeOutputFlag)
+	rootCmd.RegisterFlagCompletionFunc("lang", completeLangFlag)
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfileFlag)
+
 	rootCmd.AddCommand(modelCmd)
-	
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(mcpCmd)
+	rootCmd.AddCommand(batchCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(redactCmd)
+
+	rootCmd.Version = pkg.GetVersion()
+}
+
+// collectFlagOverrides records config flags given on the command line so LoadConfig applies them last
+func collectFlagOverrides(cmd *cobra.Command) {
+	for _, field := range configFields {
+		if field.Flag == "" {
+			continue
+		}
+		if flag := cmd.Flags().Lookup(field.Flag); flag != nil && flag.Changed {
+			flagOverrides[field.Flag] = flag.Value.String()
+		}
+	}
+}
+
+// setupOutput applies the configured theme and the color and emoji preferences
+func setupOutput(config *Config) {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		pkg.DisableColor()
+	}
+	if config == nil {
+		pkg.SetEmoji(!noEmoji)
+		return
+	}
+	pkg.SetEmoji(!noEmoji && config.EmojiEnabled())
+	pkg.SetLanguage(config.GetLanguage())
+
+	if theme, err := config.GetTheme(); err != nil {
+		fmt.Fprintln(os.Stderr, pkg.YellowBold("Warning: ")+err.Error()+", using default theme")
+	} else {
+		pkg.SetTheme(theme)
+	}
+
+	// Warnings go to stderr so they never end up in piped output
+	for _, warning := range config.Warnings() {
+		fmt.Fprintln(os.Stderr, pkg.YellowBold("Warning: ")+warning)
+	}
+}
+
+func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(pkg.RedBold("Error: ") + err.Error())
 		os.Exit(1)
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// Limits on what a single API request may ask of the server
+const (
+	maxRequestBytes     = 64 << 10
+	maxHistoryLimit     = 500
+	shutdownGracePeriod = 10 * time.Second
+)
+
+var (
+	serveAddr          string
+	serveToken         string
+	serveMaxConcurrent int
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve research over a local HTTP API",
+	Long: `Serve photon research as JSON over HTTP for editor plugins and dashboards.
+
+Endpoints:
+  POST /research          {"query": "...", "model": "...", "template": "..."}
+  POST /research/stream   same body, answered with server-sent events
+  GET  /models            available models
+  GET  /history           recent research, newest first (?limit=N); needs 'history' set to true
+
+POST bodies must be sent with "Content-Type: application/json".
+
+Set --token or PHOTON_SERVE_TOKEN to require "Authorization: Bearer <token>". Without a token,
+requests from web browsers are refused, so a page you visit cannot research on your API key.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := mustLoadConfig()
+		if err := config.Validate(); err != nil {
+			fmt.Println(pkg.RedBold("Configuration error: ") + err.Error())
+			os.Exit(1)
+		}
+		client, err := config.NewClient()
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		token := serveToken
+		if token == "" {
+			token = os.Getenv("PHOTON_SERVE_TOKEN")
+		}
+		if serveMaxConcurrent < 1 {
+			fmt.Println(pkg.RedBold("Error: ") + "--max-concurrent must be at least 1")
+			os.Exit(1)
+		}
+
+		listener, err := net.Listen("tcp", serveAddr)
+		if err != nil {
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		}
+
+		server := &researchServer{
+			config: config,
+			client: client,
+			token:  token,
+			slots:  make(chan struct{}, serveMaxConcurrent),
+		}
+		httpServer := &http.Server{
+			Handler:           server.routes(),
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+		}
+
+		fmt.Println(pkg.GreenBold("Serving photon on ") + "http://" + listener.Addr().String())
+		if token == "" && !isLoopback(listener.Addr()) {
+			fmt.Fprintln(os.Stderr, pkg.YellowBold("Warning: ")+"listening beyond localhost without a token; set --token or PHOTON_SERVE_TOKEN")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- httpServer.Serve(listener)
+		}()
+
+		select {
+		case err := <-errs:
+			fmt.Println(pkg.RedBold("Error: ") + err.Error())
+			os.Exit(1)
+		case <-ctx.Done():
+		}
+
+		// Let in-flight research finish, but stop waiting after the grace period
+		fmt.Println(pkg.Muted("Shutting down..."))
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			fmt.Println(pkg.RedBold("Error shutting down: ") + err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+// researchServer answers API requests with the configured client
+type researchServer struct {
+	config *Config
+	client *pkg.Client
+	token  string
+	// slots bounds how many research requests run at once
+	slots chan struct{}
+}
+
+// researchRequest is the body of a research request; empty fields use the configured defaults
+type researchRequest struct {
+	Query    string `json:"query"`
+	Model    string `json:"model"`
+	Template string `json:"template"`
+}
+
+// researchResult is a research answer as returned by the API
+type researchResult struct {
+	ID       string `json:"id,omitempty"`
+	Query    string `json:"query"`
+	Model    string `json:"model"`
+	Template string `json:"template"`
+	pkg.FormattedResponse
+}
+
+// modelInfo describes a model as returned by GET /models
+type modelInfo struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Provider      string   `json:"provider"`
+	Description   string   `json:"description"`
+	Features      []string `json:"features"`
+	ContextLength int      `json:"context_length"`
+	BestFor       string   `json:"best_for"`
+	Thinking      bool     `json:"thinking"`
+	Multimodal    bool     `json:"multimodal"`
+	Current       bool     `json:"current"`
+}
+
+// routes returns the API handler with authentication and request logging applied
+func (s *researchServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /research", s.handleResearch)
+	mux.HandleFunc("POST /research/stream", s.handleStream)
+	mux.HandleFunc("GET /models", s.handleModels)
+	mux.HandleFunc("GET /history", s.handleHistory)
+	return s.logRequests(s.authenticate(mux))
+}
+
+// authenticate rejects requests without the bearer token when one is configured. Without a
+// token it rejects browser requests instead, since any page the user visits could send them;
+// browsers cannot add the token to cross-origin requests, as the server allows no CORS.
+func (s *researchServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" && isBrowserRequest(r) {
+			writeError(w, http.StatusForbidden, "browser requests need a token; start the server with --token or PHOTON_SERVE_TOKEN")
+			return
+		}
+		if s.token != "" {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isBrowserRequest reports whether a request was sent by a web page rather than typed into
+// the address bar or sent by a program. Browsers mark cross-origin and script requests
+// with Origin and every request with Sec-Fetch-Site.
+func isBrowserRequest(r *http.Request) bool {
+	if r.Header.Get("Origin") != "" {
+		return true
+	}
+	site := r.Header.Get("Sec-Fetch-Site")
+	return site != "" && site != "none"
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the Flusher of the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// logRequests logs each request with its status and duration
+func (s *researchServer) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		logger.Info("serve request", "method", r.Method, "path", r.URL.Path, "status", recorder.status, "duration", time.Since(start), "remote", r.RemoteAddr)
+	})
+}
+
+// handleResearch answers POST /research with the parsed research result
+func (s *researchServer) handleResearch(w http.ResponseWriter, r *http.Request) {
+	req, client, ok := s.prepare(w, r)
+	if !ok {
+		return
+	}
+	defer s.release()
+
+	result, err := client.Research(r.Context(), req.Query)
+	if err != nil {
+		writeError(w, researchErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s.finish(client, req, result))
+}
+
+// handleStream answers with server-sent events: "chunk" events carry pieces of the answer
+// as {"text": "..."}, followed by a "result" event with the parsed result or an "error" event
+func (s *researchServer) handleStream(w http.ResponseWriter, r *http.Request) {
+	req, client, ok := s.prepare(w, r)
+	if !ok {
+		return
+	}
+	defer s.release()
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		controller.Flush()
+	}
+
+	result, err := client.ResearchStream(r.Context(), req.Query, func(chunk string) {
+		send("chunk", map[string]string{"text": chunk})
+	})
+	if err != nil {
+		send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	send("result", s.finish(client, req, result))
+}
+
+// prepare decodes a research request, picks the client for its model and template and
+// takes a concurrency slot. It writes the error response itself when it fails.
+func (s *researchServer) prepare(w http.ResponseWriter, r *http.Request) (researchRequest, *pkg.Client, bool) {
+	var req researchRequest
+	// Browsers send other content types without a CORS preflight, so only JSON is accepted
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return req, nil, false
+	}
+	body := http.MaxBytesReader(w, r.Body, maxRequestBytes)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxRequestBytes))
+		} else {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		}
+		return req, nil, false
+	}
+
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, "query is required")
+		return req, nil, false
+	}
+	if len(req.Query) > maxRequestBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("query exceeds %d bytes", maxRequestBytes))
+		return req, nil, false
+	}
+
+	client, err := s.client.With(pkg.WithModel(req.Model), pkg.WithTemplate(req.Template))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return req, nil, false
+	}
+
+	// Refuse rather than queue, so callers see back-pressure instead of timing out
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, "too many research requests in progress")
+		return req, nil, false
+	}
+	return req, client, true
+}
+
+// release frees the concurrency slot taken by prepare
+func (s *researchServer) release() {
+	<-s.slots
+}
+
+// finish records a research result in the history and shapes it for the response
+func (s *researchServer) finish(client *pkg.Client, req researchRequest, result pkg.FormattedResponse) researchResult {
+	return newResearchResult(s.config, client, "serve", req.Query, result)
+}
+
+// newResearchResult records a research result in the history and shapes it for API responses
+func newResearchResult(config *Config, client *pkg.Client, source string, query string, result pkg.FormattedResponse) researchResult {
+	if result.KeyPoints == nil {
+		result.KeyPoints = []string{}
+	}
+	return researchResult{
+		ID:                saveResearch(config, client, source, query, result),
+		Query:             query,
+		Model:             answeringModel(client, result),
+		Template:          client.Template(),
+		FormattedResponse: result,
+	}
+}
+
+// handleModels lists the available models in display order
+func (s *researchServer) handleModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, describeModels(s.client.Model()))
+}
+
+// describeModels lists the available models in display order, marking the current one
+func describeModels(current string) []modelInfo {
+	models := pkg.GetAvailableModels()
+	list := []modelInfo{}
+	for _, id := range pkg.GetModelOrder() {
+		model := models[id]
+		list = append(list, modelInfo{
+			ID:            model.ID,
+			Name:          model.Name,
+			Provider:      model.Provider,
+			Description:   model.Description,
+			Features:      model.Features,
+			ContextLength: model.ContextLen,
+			BestFor:       model.BestFor,
+			Thinking:      model.IsThinking,
+			Multimodal:    model.IsMultimodal,
+			Current:       model.ID == current,
+		})
+	}
+	return list
+}
+
+// handleHistory lists recorded research, newest first, limited by ?limit=N
+func (s *researchServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = min(n, maxHistoryLimit)
+	}
+
+	entries, err := readHistory()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []historyEntry{}
+	}
+	writeJSON(w, http.StatusOK, entries[:min(limit, len(entries))])
+}
+
+// researchErrorStatus maps a failed research call to a response status
+func researchErrorStatus(err error) int {
+	var statusErr *pkg.StatusError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.As(err, &statusErr) && statusErr.Status == http.StatusTooManyRequests:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusBadGateway
+}
+
+// writeJSON writes v as an indented JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeError writes an error response as {"error": "..."}
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// isLoopback reports whether a listener only accepts local connections
+func isLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8787", "Address to listen on; use :8787 to accept connections from other hosts")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Require this bearer token on every request (also PHOTON_SERVE_TOKEN)")
+	serveCmd.Flags().IntVar(&serveMaxConcurrent, "max-concurrent", 4, "Maximum number of research requests answered at once")
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jacky040124/photon/pkg"
+)
+
+// newTestServer serves the research API with the given token and concurrency, answering
+// from a fake API
+func newTestServer(t *testing.T, token string, slots int, config *Config) (*httptest.Server, *fakeAPI) {
+	t.Helper()
+	api, client := newFakeAPI(t)
+	server := httptest.NewServer((&researchServer{
+		config: config,
+		client: client,
+		token:  token,
+		slots:  make(chan struct{}, slots),
+	}).routes())
+	t.Cleanup(server.Close)
+	return server, api
+}
+
+// serveRequest sends a request to the test server and returns its status and body
+func serveRequest(t *testing.T, server *httptest.Server, method string, path string, body string, header map[string]string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestServeAuthenticate(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		header     map[string]string
+		wantStatus int
+	}{
+		{"no token, program", "", nil, http.StatusOK},
+		{"no token, page script", "", map[string]string{"Origin": "https://example.com"}, http.StatusForbidden},
+		{"no token, cross-site fetch", "", map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"no token, address bar", "", map[string]string{"Sec-Fetch-Site": "none"}, http.StatusOK},
+		{"token missing", "secret", nil, http.StatusUnauthorized},
+		{"token wrong", "secret", map[string]string{"Authorization": "Bearer secreT"}, http.StatusUnauthorized},
+		{"token without scheme", "secret", map[string]string{"Authorization": "secret"}, http.StatusUnauthorized},
+		{"token prefix", "secret", map[string]string{"Authorization": "Bearer secre"}, http.StatusUnauthorized},
+		{"token right", "secret", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"token right from a page", "secret", map[string]string{"Authorization": "Bearer secret", "Origin": "https://example.com"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, tt.token, 1, &Config{})
+			resp, body := serveRequest(t, server, http.MethodGet, "/models", "", tt.header)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", resp.Header.Get("WWW-Authenticate"))
+			}
+			if resp.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestServeResearch(t *testing.T) {
+	const jsonType = "application/json"
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{"answer", http.MethodPost, "/research", jsonType, `{"query": "What is Go?"}`, http.StatusOK, "Answer from"},
+		{"JSON with charset", http.MethodPost, "/research", jsonType + "; charset=utf-8", `{"query": "What is Go?"}`, http.StatusOK, "Answer from"},
+		{"template", http.MethodPost, "/research", jsonType, `{"query": "What is Go?", "template": "brief"}`, http.StatusOK, `"template": "brief"`},
+		{"form body", http.MethodPost, "/research", "application/x-www-form-urlencoded", `query=What+is+Go`, http.StatusUnsupportedMediaType, "application/json"},
+		{"text body", http.MethodPost, "/research", "text/plain", `{"query": "What is Go?"}`, http.StatusUnsupportedMediaType, "application/json"},
+		{"no content type", http.MethodPost, "/research", "", `{"query": "What is Go?"}`, http.StatusUnsupportedMediaType, "application/json"},
+		{"stream needs JSON too", http.MethodPost, "/research/stream", "text/plain", `{"query": "What is Go?"}`, http.StatusUnsupportedMediaType, "application/json"},
+		{"body too large", http.MethodPost, "/research", jsonType, `{"query": "` + strings.Repeat("a", maxRequestBytes) + `"}`, http.StatusRequestEntityTooLarge, "exceeds"},
+		{"invalid JSON", http.MethodPost, "/research", jsonType, `{"query": `, http.StatusBadRequest, "invalid JSON body"},
+		{"blank query", http.MethodPost, "/research", jsonType, `{"query": "  "}`, http.StatusBadRequest, "query is required"},
+		{"unknown model", http.MethodPost, "/research", jsonType, `{"query": "What is Go?", "model": "no-such-model"}`, http.StatusBadRequest, "no-such-model"},
+		{"unknown template", http.MethodPost, "/research", jsonType, `{"query": "What is Go?", "template": "no-such-template"}`, http.StatusBadRequest, "no-such-template"},
+		{"GET stream is gone", http.MethodGet, "/research/stream?query=Go", "", "", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, "", 1, &Config{})
+			header := map[string]string{}
+			if tt.contentType != "" {
+				header["Content-Type"] = tt.contentType
+			}
+			resp, body := serveRequest(t, server, tt.method, tt.path, tt.body, header)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServeTooManyRequests(t *testing.T) {
+	server, api := newTestServer(t, "", 1, &Config{})
+	// Block a model other than the default, so other requests are still answered
+	blocking := pkg.GetModelOrder()[0]
+	if blocking == pkg.GetDefaultModel() {
+		blocking = pkg.GetModelOrder()[1]
+	}
+	api.blocking = apiName(t, blocking)
+	header := map[string]string{"Content-Type": "application/json"}
+
+	// Hold the only slot with a request the fake API never answers
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/research", strings.NewReader(`{"query": "slow", "model": "`+blocking+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if resp, err := server.Client().Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-api.started
+
+	resp, body := serveRequest(t, server, http.MethodPost, "/research", `{"query": "fast"}`, header)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d: %s", resp.StatusCode, http.StatusTooManyRequests, body)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("429 response has no Retry-After header")
+	}
+
+	// Once the slow request is cancelled its slot is free again, after the handler notices
+	cancel()
+	<-done
+	for range 100 {
+		resp, body = serveRequest(t, server, http.MethodPost, "/research", `{"query": "fast"}`, header)
+		if resp.StatusCode != http.StatusTooManyRequests {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status after release = %d, want %d: %s", resp.StatusCode, http.StatusOK, body)
+	}
+}
+
+func TestServeHistory(t *testing.T) {
+	enabled := true
+	tests := []struct {
+		name       string
+		config     *Config
+		query      string
+		wantStatus int
+		wantCount  int
+	}{
+		{"recording off", &Config{}, "", http.StatusOK, 0},
+		{"recording on", &Config{History: &enabled}, "", http.StatusOK, 2},
+		{"limit", &Config{History: &enabled}, "?limit=1", http.StatusOK, 1},
+		{"zero limit", &Config{History: &enabled}, "?limit=0", http.StatusBadRequest, 0},
+		{"limit not a number", &Config{History: &enabled}, "?limit=ten", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, "", 1, tt.config)
+			header := map[string]string{"Content-Type": "application/json"}
+			for _, query := range []string{"first", "second"} {
+				if resp, body := serveRequest(t, server, http.MethodPost, "/research", `{"query": "`+query+`"}`, header); resp.StatusCode != http.StatusOK {
+					t.Fatalf("research status = %d: %s", resp.StatusCode, body)
+				}
+			}
+
+			resp, body := serveRequest(t, server, http.MethodGet, "/history"+tt.query, "", nil)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var entries []historyEntry
+			if err := json.Unmarshal([]byte(body), &entries); err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tt.wantCount {
+				t.Fatalf("got %d entries, want %d", len(entries), tt.wantCount)
+			}
+			if len(entries) > 0 && entries[0].Query != "second" {
+				t.Errorf("newest entry = %q, want %q", entries[0].Query, "second")
+			}
+		})
+	}
+}
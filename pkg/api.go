@@ -1,19 +1,38 @@
 package pkg
 
 import (
-	"bytes"
-	"encoding/json"
+	"cmp"
+	"context"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// FormattedResponse is a parsed research answer. Summary and each key point hold
+// Markdown, so code blocks, tables and lists survive until rendering.
 type FormattedResponse struct {
-	Summary     string
-	KeyPoints   []string
-	SourceLinks []string
+	Summary     string   `json:"summary"`
+	KeyPoints   []string `json:"key_points"`
+	SourceLinks []string `json:"source_links,omitempty"`
+	// Usage is how many tokens the answer took, when the API reported it
+	Usage *Usage `json:"usage,omitempty"`
+	// Route is the model the auto model picked and why, or nil for a fixed model
+	Route *Route `json:"route,omitempty"`
+	// Warnings are problems with the query that did not stop it being answered, such as
+	// trimming it to fit the model's context window
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Usage is the token count the API reports for a chat completion
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 type APIResponse struct {
@@ -22,95 +41,44 @@ type APIResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"`
 }
 
+// Format formats a query response using the default model
 func Format(query string) FormattedResponse {
-	content, err := CallLLMAPI(query)
-	if err != nil {
-		return FormattedResponse{
-			Summary: fmt.Sprintf("Error fetching research: %s", err.Error()),
-		}
-	}
-
-	var result FormattedResponse
-	lines := strings.Split(content, "\n")
-
-	// More robust parsing
-	currentSection := ""
-	summaryLines := []string{}
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if line == "" {
-			continue
-		}
+	return FormatWithModel(query, GetDefaultModel())
+}
 
-		// Detect sections
-		lowerLine := strings.ToLower(line)
-		if strings.Contains(lowerLine, "summary") && strings.Contains(lowerLine, ":") {
-			currentSection = "summary"
-			continue
-		} else if strings.Contains(lowerLine, "key point") && strings.Contains(lowerLine, ":") {
-			currentSection = "keypoints"
-			continue
-		}
+// FormatWithModel formats a query response using a specific model
+func FormatWithModel(query string, modelID string) FormattedResponse {
+	return FormatWithTemplate(query, modelID, GetDefaultTemplate())
+}
 
-		// Process content based on current section
-		switch currentSection {
-		case "summary":
-			// Only collect clean summary lines (no numbered points)
-			if !strings.HasPrefix(line, "1.") && !strings.HasPrefix(line, "2.") &&
-				!strings.HasPrefix(line, "3.") && !strings.HasPrefix(line, "4.") &&
-				!strings.HasPrefix(line, "5.") && !strings.Contains(line, "➤") {
-				summaryLines = append(summaryLines, line)
-			}
-		case "keypoints":
-			cleanLine := strings.TrimLeft(line, "0123456789-.*• ➤")
-			cleanLine = strings.TrimSpace(cleanLine)
-			if cleanLine != "" && !strings.HasPrefix(strings.ToLower(cleanLine), "key point") {
-				result.KeyPoints = append(result.KeyPoints, cleanLine)
-			}
-		default:
-			// If no section detected yet, treat as summary
-			if result.Summary == "" && currentSection == "" {
-				summaryLines = append(summaryLines, line)
-			}
-		}
-	}
+// FormatWithTemplate formats a query response using a specific model and prompt template
+func FormatWithTemplate(query string, modelID string, templateName string) FormattedResponse {
+	return FormatWithOptions(query, RequestOptions{Model: modelID, Template: templateName})
+}
 
-	// Join summary lines
-	if len(summaryLines) > 0 {
-		result.Summary = strings.Join(summaryLines, " ")
+// FormatWithOptions formats a query response using the given request options.
+// Errors are reported in the summary.
+func FormatWithOptions(query string, opts RequestOptions) FormattedResponse {
+	client, err := opts.client()
+	if err != nil {
+		return ErrorResponse(err)
 	}
 
-	// If parsing failed, use the content as summary but try to clean it
-	if result.Summary == "" {
-		// Remove obvious key points from summary
-		cleanedContent := strings.ReplaceAll(content, "\n\n", " ")
-		cleanedContent = strings.ReplaceAll(cleanedContent, "\n", " ")
-		result.Summary = cleanedContent
+	result, err := client.Research(context.Background(), query)
+	if err != nil {
+		return ErrorResponse(err)
 	}
-
 	return result
 }
 
-// FormatWithModel formats a query response using a specific model
-func FormatWithModel(query string, modelID string) FormattedResponse {
-	content, err := CallLLMAPIWithModel(query, modelID)
-	if err != nil {
-		return FormattedResponse{
-			Summary: fmt.Sprintf("Error fetching research: %s", err.Error()),
-		}
+// ErrorResponse reports a failed research query in place of its answer
+func ErrorResponse(err error) FormattedResponse {
+	return FormattedResponse{
+		Summary: fmt.Sprintf("Error fetching research: %s. Run `ptn doctor` to diagnose.", err.Error()),
 	}
-
-	// Handle thinking models specially
-	model, _ := GetModel(modelID)
-	if model != nil && model.IsThinking {
-		content = processThinkingModelResponse(content)
-	}
-
-	return parseResponse(content)
 }
 
 // processThinkingModelResponse extracts the final answer from thinking model output
@@ -118,7 +86,7 @@ func processThinkingModelResponse(content string) string {
 	// Remove <think>...</think> sections for cleaner output
 	thinkStart := "<think>"
 	thinkEnd := "</think>"
-	
+
 	for {
 		startIdx := strings.Index(content, thinkStart)
 		if startIdx == -1 {
@@ -129,70 +97,149 @@ func processThinkingModelResponse(content string) string {
 			break
 		}
 		endIdx += startIdx + len(thinkEnd)
-		
+
 		// Remove the thinking section
 		content = content[:startIdx] + content[endIdx:]
 	}
-	
+
 	return strings.TrimSpace(content)
 }
 
-// parseResponse handles the common response parsing logic
-func parseResponse(content string) FormattedResponse {
+// parseResponse splits model output into summary and key point sections,
+// keeping the Markdown of each section intact (code blocks, tables, lists)
+func parseResponse(content string, log *slog.Logger) FormattedResponse {
 	var result FormattedResponse
-	lines := strings.Split(content, "\n")
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
 
 	currentSection := ""
 	summaryLines := []string{}
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		lowerLine := strings.ToLower(line)
-		if strings.Contains(lowerLine, "summary") && strings.Contains(lowerLine, ":") {
-			currentSection = "summary"
-			continue
-		} else if strings.Contains(lowerLine, "key point") && strings.Contains(lowerLine, ":") {
-			currentSection = "keypoints"
-			continue
+	inCode := false
+
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+
+		// Lines inside fenced code blocks are kept verbatim and never start a section
+		if isCodeFence(line) {
+			inCode = !inCode
+		} else if !inCode {
+			if section, ok := detectSection(line); ok {
+				log.Debug("parser: section header", "line", i+1, "text", line, "section", section)
+				currentSection = section
+				continue
+			}
 		}
+		fenced := inCode || isCodeFence(line)
 
 		switch currentSection {
-		case "summary":
-			if !strings.HasPrefix(line, "1.") && !strings.HasPrefix(line, "2.") &&
-				!strings.HasPrefix(line, "3.") && !strings.HasPrefix(line, "4.") &&
-				!strings.HasPrefix(line, "5.") && !strings.Contains(line, "➤") {
-				summaryLines = append(summaryLines, line)
+		case "sources":
+			if link := sourceURLPattern.FindString(line); link != "" && !fenced {
+				log.Debug("parser: source link", "line", i+1, "url", link)
+				result.SourceLinks = append(result.SourceLinks, strings.TrimRight(link, ".,;:!?)]>*_"))
 			}
 		case "keypoints":
-			cleanLine := strings.TrimLeft(line, "0123456789-.*• ➤")
-			cleanLine = strings.TrimSpace(cleanLine)
-			if cleanLine != "" && !strings.HasPrefix(strings.ToLower(cleanLine), "key point") {
-				result.KeyPoints = append(result.KeyPoints, cleanLine)
+			if !fenced && line == "" {
+				continue
 			}
-		default:
-			if result.Summary == "" && currentSection == "" {
-				summaryLines = append(summaryLines, line)
+			if !fenced && isKeyPointStart(raw) {
+				point := strings.TrimSpace(strings.TrimLeft(line, "0123456789-.)*•➤、． "))
+				if point != "" {
+					log.Debug("parser: key point", "line", i+1, "index", len(result.KeyPoints))
+					result.KeyPoints = append(result.KeyPoints, point)
+				}
+				continue
+			}
+			if len(result.KeyPoints) == 0 {
+				result.KeyPoints = append(result.KeyPoints, "")
 			}
+			last := len(result.KeyPoints) - 1
+			result.KeyPoints[last] = strings.TrimLeft(result.KeyPoints[last]+"\n"+strings.TrimRight(raw, " \t"), "\n")
+		default:
+			summaryLines = append(summaryLines, strings.TrimRight(raw, " \t"))
 		}
 	}
 
-	if len(summaryLines) > 0 {
-		result.Summary = strings.Join(summaryLines, " ")
-	}
+	result.Summary = strings.TrimSpace(collapseBlankLines(summaryLines))
 
-	if result.Summary == "" {
-		cleanedContent := strings.ReplaceAll(content, "\n\n", " ")
-		cleanedContent = strings.ReplaceAll(cleanedContent, "\n", " ")
-		result.Summary = cleanedContent
+	if result.Summary == "" && len(result.KeyPoints) == 0 {
+		log.Debug("parser: no sections found, using the whole response as the summary")
+		result.Summary = strings.TrimSpace(content)
 	}
 
+	log.Debug("parser: result", "summary_chars", len(result.Summary), "key_points", len(result.KeyPoints))
 	return result
 }
 
+// sourceURLPattern finds a web address in a line of the sources section, stopping at
+// Chinese punctuation, which is often written without a space after the address
+var sourceURLPattern = regexp.MustCompile(`https?://[^\s<>"，。；！？）】]+`)
+
+// detectSection recognizes section headers such as "Summary:", "## Key Points", "**Summary:**"
+// or their Chinese counterparts such as "摘要：" and "## 要点"
+func detectSection(line string) (string, bool) {
+	header := strings.ToLower(strings.Trim(line, "#*_:：【】 "))
+	trimmed := strings.TrimRight(line, "*_ ")
+	isHeader := strings.HasPrefix(line, "#") || strings.HasSuffix(trimmed, ":") || strings.HasSuffix(trimmed, "：")
+	if !isHeader || utf8.RuneCountInString(header) > 20 {
+		return "", false
+	}
+
+	switch {
+	case strings.Contains(header, "summary") || containsAny(header, "摘要", "总结", "總結", "概要"):
+		return "summary", true
+	case strings.Contains(header, "key point") || containsAny(header, "要点", "要點", "关键点", "關鍵點", "重点", "重點"):
+		return "keypoints", true
+	case header == "sources" || header == "source" || header == "references" || containsAny(header, "来源", "來源", "参考", "參考"):
+		return "sources", true
+	}
+	return "", false
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKeyPointStart reports whether an unindented line starts a new list item
+func isKeyPointStart(raw string) bool {
+	if raw != strings.TrimLeft(raw, " \t") {
+		return false
+	}
+	return orderedItemPattern.MatchString(raw) || bulletItemPattern.MatchString(raw) || cjkItemPattern.MatchString(raw) || strings.HasPrefix(raw, "➤")
+}
+
+// cjkItemPattern matches list items numbered the Chinese way, such as "1、" or "1．"
+var cjkItemPattern = regexp.MustCompile(`^\d+[、．]`)
+
+// collapseBlankLines joins lines, folding runs of blank lines outside code blocks
+// into a single paragraph break
+func collapseBlankLines(lines []string) string {
+	var b strings.Builder
+	blank := false
+	inCode := false
+	for _, line := range lines {
+		if isCodeFence(strings.TrimSpace(line)) {
+			inCode = !inCode
+		} else if strings.TrimSpace(line) == "" && !inCode {
+			blank = true
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+			if blank {
+				b.WriteString("\n")
+			}
+		}
+		blank = false
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
 // CallLLMAPI makes a request to OpenRouter API using the default model
 func CallLLMAPI(question string) (string, error) {
 	return CallLLMAPIWithModel(question, GetDefaultModel())
@@ -200,56 +247,80 @@ func CallLLMAPI(question string) (string, error) {
 
 // CallLLMAPIWithModel makes a request to OpenRouter API using a specific model
 func CallLLMAPIWithModel(question string, modelID string) (string, error) {
-	openRouterKey := os.Getenv("PHOTON_OPEN_ROUTER_KEY")
-	if openRouterKey == "" {
-		return "", fmt.Errorf("PHOTON_OPEN_ROUTER_KEY environment variable is not set")
-	}
+	return CallLLMAPIWithTemplate(question, modelID, GetDefaultTemplate())
+}
+
+// CallLLMAPIWithTemplate makes a request to OpenRouter API using a specific model and prompt template
+func CallLLMAPIWithTemplate(question string, modelID string, templateName string) (string, error) {
+	return CallLLMAPIWithOptions(question, RequestOptions{Model: modelID, Template: templateName})
+}
 
-	// Get model details
-	model, err := GetModel(modelID)
+// CallLLMAPIWithOptions makes a request to OpenRouter API using the given request options
+func CallLLMAPIWithOptions(question string, opts RequestOptions) (string, error) {
+	client, err := opts.client()
 	if err != nil {
-		return "", fmt.Errorf("invalid model: %s", err.Error())
+		return "", err
 	}
+	return client.Complete(context.Background(), question)
+}
 
-	// Create system prompt based on model capabilities
-	systemPrompt := "You are a research assistant that provides structured, factual information. Format your response with clear sections using exactly these headers: 'Summary:' and 'Key Points:'. Use emojis sparingly and only where they enhance understanding."
-	
-	userPrompt := question + "\n\nPlease structure your response as follows:\n\nSummary:\n[Provide a concise 2-3 sentence summary without numbered points]\n\nKey Points:\n1. [First key point]\n2. [Second key point]\n3. [Third key point]"
+// DefaultBaseURL is the OpenRouter API base URL
+const DefaultBaseURL = "https://openrouter.ai/api/v1"
+
+// DefaultTimeout bounds how long a research request may take
+const DefaultTimeout = 15 * time.Second
+
+// RequestOptions controls how a research query is sent by the package-level helpers.
+// Zero values fall back to defaults. New code should use a Client instead.
+type RequestOptions struct {
+	Model    string
+	Template string
+	BaseURL  string
+	Timeout  time.Duration
+	// APIKey defaults to the PHOTON_OPEN_ROUTER_KEY environment variable
+	APIKey string
+	// HTTPClient defaults to a client with default connection settings
+	HTTPClient *http.Client
+	// NoRedact sends queries as is instead of redacting them with the built-in rules
+	NoRedact bool
+}
 
-	// Special handling for thinking models
-	if model.IsThinking {
-		systemPrompt = "You are a research assistant that provides structured, factual information. Use your reasoning capabilities to analyze the query thoroughly. You can use <think> tags to show your reasoning process, then provide a clear final answer with 'Summary:' and 'Key Points:' sections."
-		userPrompt = question + "\n\nPlease think through this query step by step, then provide your response in this format:\n\nSummary:\n[Provide a concise 2-3 sentence summary]\n\nKey Points:\n1. [First key point]\n2. [Second key point]\n3. [Third key point]"
-	}
+// errNoAPIKey explains how to configure a key when the package-level helpers have none
+var errNoAPIKey = fmt.Errorf("%w; run 'ptn auth login' or set PHOTON_OPEN_ROUTER_KEY", ErrNoAPIKey)
 
-	payload := map[string]interface{}{
-		"model": model.APIName,
-		"messages": []map[string]string{
-			{"role": "system", "content": systemPrompt},
-			{"role": "user", "content": userPrompt},
-		},
+// client builds a Client from the options, reading the API key from the environment if unset
+func (o RequestOptions) client() (*Client, error) {
+	if o.APIKey == "" {
+		o.APIKey = os.Getenv("PHOTON_OPEN_ROUTER_KEY")
 	}
-
-	jsonBody, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(jsonBody))
-	req.Header.Set("Authorization", "Bearer "+openRouterKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("HTTP-Referer", "https://github.com/photon-research-tool")
-	req.Header.Set("X-Title", "Photon Research Tool")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
+	if o.APIKey == "" {
+		return nil, errNoAPIKey
 	}
+	var redactor *Redactor
+	if !o.NoRedact {
+		redactor, _ = NewRedactor()
+	}
+	return NewClient(
+		WithRedactor(redactor),
+		WithAPIKey(o.APIKey),
+		WithBaseURL(o.BaseURL),
+		WithModel(o.Model),
+		WithTemplate(o.Template),
+		WithHTTPClient(o.HTTPClient),
+		WithTimeout(cmp.Or(o.Timeout, DefaultTimeout)),
+	)
+}
 
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
+// BuildFollowUpQuery builds a query that carries the previous exchange as context
+func BuildFollowUpQuery(previousQuery string, previous FormattedResponse, followUp string) string {
+	var b strings.Builder
 
-	var response APIResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil || len(response.Choices) == 0 {
-		return string(body), nil // Return raw response if parsing fails
+	b.WriteString("Previous question: " + previousQuery + "\n")
+	b.WriteString("Previous answer: " + previous.Summary + "\n")
+	for _, point := range previous.KeyPoints {
+		b.WriteString("- " + point + "\n")
 	}
+	b.WriteString("\nFollow-up question: " + followUp)
 
-	return response.Choices[0].Message.Content, nil
+	return b.String()
 }
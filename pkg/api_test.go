@@ -0,0 +1,142 @@
+package pkg
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    FormattedResponse
+	}{
+		{
+			name:    "plain sections",
+			content: "Summary:\nGo is a language.\n\nKey Points:\n1. Fast\n2. Simple",
+			want:    FormattedResponse{Summary: "Go is a language.", KeyPoints: []string{"Fast", "Simple"}},
+		},
+		{
+			name:    "markdown headers and bullets",
+			content: "## Summary\nGo is **compiled**.\n\n## Key Points\n- Uses `go build`\n- Has [docs](https://go.dev)",
+			want:    FormattedResponse{Summary: "Go is **compiled**.", KeyPoints: []string{"Uses `go build`", "Has [docs](https://go.dev)"}},
+		},
+		{
+			name:    "summary keeps paragraphs and folds extra blank lines",
+			content: "Summary:\nFirst paragraph.\n\n\n\nSecond paragraph.",
+			want:    FormattedResponse{Summary: "First paragraph.\n\nSecond paragraph."},
+		},
+		{
+			name:    "code block is kept verbatim",
+			content: "Summary:\nRun this:\n```go\n// Summary:\nfmt.Println(1)\n\n\nfmt.Println(2)\n```",
+			want:    FormattedResponse{Summary: "Run this:\n```go\n// Summary:\nfmt.Println(1)\n\n\nfmt.Println(2)\n```"},
+		},
+		{
+			name:    "key point continues with indented lines and code",
+			content: "Key Points:\n1. Install it:\n   ```sh\n   go install\n   ```\n2. Run it",
+			want:    FormattedResponse{KeyPoints: []string{"Install it:\n   ```sh\n   go install\n   ```", "Run it"}},
+		},
+		{
+			name:    "table stays in the summary",
+			content: "Summary:\n| a | b |\n|---|---|\n| 1 | 2 |\n\nKey Points:\n- One",
+			want:    FormattedResponse{Summary: "| a | b |\n|---|---|\n| 1 | 2 |", KeyPoints: []string{"One"}},
+		},
+		{
+			name:    "sources collect links",
+			content: "Summary:\nText.\n\nSources:\n- https://go.dev/doc.\n- See (https://pkg.go.dev)",
+			want:    FormattedResponse{Summary: "Text.", SourceLinks: []string{"https://go.dev/doc", "https://pkg.go.dev"}},
+		},
+		{
+			name:    "no sections uses the whole response",
+			content: "  Just an answer.\n",
+			want:    FormattedResponse{Summary: "Just an answer."},
+		},
+		{
+			name:    "windows line endings",
+			content: "Summary:\r\nGo.\r\n\r\nKey Points:\r\n1. Fast\r\n",
+			want:    FormattedResponse{Summary: "Go.", KeyPoints: []string{"Fast"}},
+		},
+	}
+
+	log := slog.New(slog.DiscardHandler)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseResponse(tt.content, log); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseResponse() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessThinkingModelResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no thinking", "Answer", "Answer"},
+		{"thinking removed", "<think>hmm</think>\nAnswer", "Answer"},
+		{"several sections", "<think>a</think>One <think>b</think>Two", "One Two"},
+		{"unclosed section kept", "<think>hmm\nAnswer", "<think>hmm\nAnswer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := processThinkingModelResponse(tt.content); got != tt.want {
+				t.Errorf("processThinkingModelResponse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSection(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    string
+		wantHit bool
+	}{
+		{"Summary:", "summary", true},
+		{"## Summary", "summary", true},
+		{"**Summary:**", "summary", true},
+		{"Key Points:", "keypoints", true},
+		{"### Key Points", "keypoints", true},
+		{"Sources:", "sources", true},
+		{"References:", "sources", true},
+		{"摘要：", "summary", true},
+		{"摘要:", "summary", true},
+		{"## 摘要", "summary", true},
+		{"**摘要：**", "summary", true},
+		{"【摘要】：", "summary", true},
+		{"总结：", "summary", true},
+		{"要点：", "keypoints", true},
+		{"## 要点", "keypoints", true},
+		{"**关键要点：**", "keypoints", true},
+		{"要點：", "keypoints", true},
+		{"参考来源：", "sources", true},
+		{"摘要", "", false},
+		{"要点是保持简单", "", false},
+		{"这是一段很长的摘要说明文字，它不是一个标题而是正文内容：", "", false},
+		{"The summary of this is:", "", false},
+		{"Go is simple.", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := detectSection(tt.line)
+			if got != tt.want || ok != tt.wantHit {
+				t.Errorf("detectSection(%q) = %q, %v, want %q, %v", tt.line, got, ok, tt.want, tt.wantHit)
+			}
+		})
+	}
+}
+
+func TestParseResponseChinese(t *testing.T) {
+	content := "## 摘要\nGo 是一门编译型语言。\n\n## 要点\n1、简单\n2．快速\n- 并发\n\n参考来源：\n- https://go.dev/doc，官方文档"
+	want := FormattedResponse{
+		Summary:     "Go 是一门编译型语言。",
+		KeyPoints:   []string{"简单", "快速", "并发"},
+		SourceLinks: []string{"https://go.dev/doc"},
+	}
+	if got := parseResponse(content, slog.New(slog.DiscardHandler)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseResponse() = %#v, want %#v", got, want)
+	}
+}
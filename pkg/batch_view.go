@@ -0,0 +1,180 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// States of an item in a batch run
+const (
+	BatchPending = iota
+	BatchRunning
+	BatchWaiting
+	BatchDone
+	BatchFailed
+	BatchSkipped
+)
+
+// BatchItem is one query of a batch run and how far it got
+type BatchItem struct {
+	Query  string
+	Status int
+	// Detail explains the status, such as an error or how long a rate limit lasts
+	Detail   string
+	Duration time.Duration
+}
+
+// BatchItemMsg reports a status change of the batch item at Index
+type BatchItemMsg struct {
+	Index    int
+	Status   int
+	Detail   string
+	Duration time.Duration
+}
+
+// BatchDoneMsg reports that every batch item has finished
+type BatchDoneMsg struct{}
+
+// BatchProgressModel shows the progress of a batch run with the status of each item
+type BatchProgressModel struct {
+	items    []BatchItem
+	spinner  spinner.Model
+	start    time.Time
+	finished bool
+	width    int
+	height   int
+}
+
+// NewBatchProgress creates a progress view for the given items
+func NewBatchProgress(items []BatchItem) BatchProgressModel {
+	return BatchProgressModel{
+		items:   items,
+		spinner: CreateSpinner(),
+		start:   time.Now(),
+		width:   TerminalWidth(),
+		height:  24,
+	}
+}
+
+// Init starts the spinner
+func (m BatchProgressModel) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+// Update applies item status changes and quits once the batch is done or interrupted
+func (m BatchProgressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC || msg.String() == "q" {
+			return m, tea.Quit
+		}
+	case BatchItemMsg:
+		if msg.Index >= 0 && msg.Index < len(m.items) {
+			item := &m.items[msg.Index]
+			item.Status, item.Detail, item.Duration = msg.Status, msg.Detail, msg.Duration
+		}
+	case BatchDoneMsg:
+		m.finished = true
+		return m, tea.Quit
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// Finished reports whether every item finished before the view quit
+func (m BatchProgressModel) Finished() bool {
+	return m.finished
+}
+
+// counts returns how many items are in each state
+func (m BatchProgressModel) counts() map[int]int {
+	counts := make(map[int]int)
+	for _, item := range m.items {
+		counts[item.Status]++
+	}
+	return counts
+}
+
+// View renders a progress bar, counters and a window of items around the ones in flight
+func (m BatchProgressModel) View() string {
+	counts := m.counts()
+	finished := counts[BatchDone] + counts[BatchFailed] + counts[BatchSkipped]
+	total := len(m.items)
+
+	var b strings.Builder
+	b.WriteString("\n " + CyanBold(Icon("📦")+"BATCH RESEARCH") + "  " + Muted(time.Since(m.start).Round(time.Second).String()) + "\n\n")
+
+	barWidth := max(min(m.width-12, 60), 10)
+	filled := 0
+	if total > 0 {
+		filled = barWidth * finished / total
+	}
+	b.WriteString(" " + Cyan(strings.Repeat("█", filled)) + Muted(strings.Repeat("░", barWidth-filled)) + fmt.Sprintf(" %d/%d\n", finished, total))
+
+	summary := fmt.Sprintf(" %s  %s  %s", Green(fmt.Sprintf("%d done", counts[BatchDone])), Red(fmt.Sprintf("%d failed", counts[BatchFailed])), Cyan(fmt.Sprintf("%d running", counts[BatchRunning])))
+	if counts[BatchWaiting] > 0 {
+		summary += "  " + YellowBold(fmt.Sprintf("%d rate limited", counts[BatchWaiting]))
+	}
+	if counts[BatchSkipped] > 0 {
+		summary += "  " + Muted(fmt.Sprintf("%d already done", counts[BatchSkipped]))
+	}
+	b.WriteString(summary + "\n\n")
+
+	// Keep the earliest unfinished item in view, with a little finished context above it
+	rows := max(m.height-8, 3)
+	first := 0
+	for i, item := range m.items {
+		if item.Status == BatchPending || item.Status == BatchRunning || item.Status == BatchWaiting {
+			first = max(i-2, 0)
+			break
+		}
+		first = max(i-rows+1, 0)
+	}
+	last := min(first+rows, total)
+
+	for i := first; i < last; i++ {
+		b.WriteString(ansi.Truncate(m.renderItem(i), m.width, "…") + "\n")
+	}
+	if last < total {
+		b.WriteString(Muted(fmt.Sprintf(" … %d more", total-last)) + "\n")
+	}
+
+	if !m.finished {
+		b.WriteString("\n" + Muted(" q/ctrl+c stop; finished results are kept and the run can be resumed") + "\n")
+	}
+	return b.String()
+}
+
+// renderItem renders one line of the item list
+func (m BatchProgressModel) renderItem(i int) string {
+	item := m.items[i]
+	query := strings.Join(strings.Fields(item.Query), " ")
+	line := fmt.Sprintf("%4d  ", i+1)
+
+	switch item.Status {
+	case BatchPending:
+		return " " + Muted("·") + Muted(line+query)
+	case BatchRunning:
+		return m.spinner.View() + line + query
+	case BatchWaiting:
+		return " " + YellowBold("…") + line + query + "  " + YellowBold(item.Detail)
+	case BatchDone:
+		return " " + Green("✓") + line + query + "  " + Muted(formatLatency(item.Duration))
+	case BatchFailed:
+		return " " + Red("✗") + line + query + "  " + Red(item.Detail)
+	case BatchSkipped:
+		return " " + Muted("↷"+line+query+"  already done")
+	}
+	return line + query
+}
@@ -0,0 +1,747 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoAPIKey is returned when a Client is created without an API key
+var ErrNoAPIKey = errors.New("no OpenRouter API key configured")
+
+// Client sends research queries to an OpenRouter-compatible API. It keeps no global
+// state and never writes to the terminal, so it can be embedded in other programs.
+// A Client is safe for concurrent use.
+type Client struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	template   string
+	lang       string
+	timeout    time.Duration
+	httpClient *http.Client
+	templates  map[string]Template
+	redactor   *Redactor
+	router     *Router
+	logger     *slog.Logger
+	preview    bool
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey sets the API key sent with every request
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithBaseURL sets the API base URL, defaulting to DefaultBaseURL
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		if url != "" {
+			c.baseURL = url
+		}
+	}
+}
+
+// WithModel sets the model queries are sent to, by its photon ID such as "deepseek-v3", or
+// AutoModel to pick a model for each query
+func WithModel(id string) Option {
+	return func(c *Client) {
+		if id != "" {
+			c.model = id
+		}
+	}
+}
+
+// WithTemplate sets the prompt template queries are sent with, by name
+func WithTemplate(name string) Option {
+	return func(c *Client) {
+		if name != "" {
+			c.template = name
+		}
+	}
+}
+
+// WithLanguage sets the language answers are asked for, such as "zh", defaulting to English
+func WithLanguage(lang string) Option {
+	return func(c *Client) {
+		if lang != "" {
+			c.lang = lang
+		}
+	}
+}
+
+// WithTemplates adds prompt templates, replacing built-in templates of the same name
+func WithTemplates(templates ...Template) Option {
+	return func(c *Client) {
+		for _, template := range templates {
+			c.templates[template.Name] = template
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for requests, such as one from NewHTTPClient
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// WithTimeout bounds each call when its context has no earlier deadline. Zero disables it.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) { c.timeout = timeout }
+}
+
+// WithLogger logs requests, responses and parser decisions. Nothing is logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithRedactor replaces secrets and personal data in queries with placeholders before they
+// are sent and puts the original values back into answers. Without it queries are sent as is.
+func WithRedactor(redactor *Redactor) Option {
+	return func(c *Client) {
+		c.redactor = redactor
+	}
+}
+
+// WithRouter sets how the auto model picks a model for each query, replacing the built-in
+// heuristics and their default models
+func WithRouter(router *Router) Option {
+	return func(c *Client) {
+		if router != nil {
+			c.router = router
+		}
+	}
+}
+
+// NewClient creates a Client. An API key is required; everything else has a default.
+func NewClient(opts ...Option) (*Client, error) {
+	return newClient(false, opts)
+}
+
+// NewPreviewClient creates a Client that redacts queries and builds prompts but never sends
+// them, for showing what a query would be sent as. It needs no API key.
+func NewPreviewClient(opts ...Option) (*Client, error) {
+	return newClient(true, opts)
+}
+
+// newClient creates a Client with the defaults and the given options
+func newClient(preview bool, opts []Option) (*Client, error) {
+	c := &Client{
+		preview:   preview,
+		baseURL:   DefaultBaseURL,
+		model:     GetDefaultModel(),
+		template:  GetDefaultTemplate(),
+		lang:      DefaultLanguage,
+		timeout:   DefaultTimeout,
+		templates: GetTemplates(),
+		logger:    slog.New(slog.DiscardHandler),
+	}
+	c.router, _ = NewRouter("")
+	return c.apply(opts)
+}
+
+// With returns a copy of the client with further options applied, such as another model
+func (c *Client) With(opts ...Option) (*Client, error) {
+	clone := *c
+	clone.templates = maps.Clone(c.templates)
+	return clone.apply(opts)
+}
+
+// apply applies options and checks that the result is usable
+func (c *Client) apply(opts []Option) (*Client, error) {
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.apiKey == "" && !c.preview {
+		return nil, ErrNoAPIKey
+	}
+	if _, err := GetModel(c.model); err != nil && c.model != AutoModel {
+		return nil, fmt.Errorf("invalid model: %w", err)
+	}
+	if _, exists := c.templates[c.template]; !exists {
+		return nil, fmt.Errorf("invalid template: template '%s' not found", c.template)
+	}
+	locale, err := GetLocale(c.lang)
+	if err != nil {
+		return nil, fmt.Errorf("invalid language: %w", err)
+	}
+	c.lang = locale.Code
+	if c.httpClient == nil {
+		c.httpClient = mustHTTPClient(HTTPConfig{})
+	}
+	c.baseURL = strings.TrimRight(c.baseURL, "/")
+	return c, nil
+}
+
+// Model returns the ID of the model queries are sent to, which may be AutoModel
+func (c *Client) Model() string {
+	return c.model
+}
+
+// Template returns the name of the prompt template queries are sent with
+func (c *Client) Template() string {
+	return c.template
+}
+
+// Language returns the code of the language answers are asked for
+func (c *Client) Language() string {
+	return c.lang
+}
+
+// Timeout returns the per-call time limit, or zero if there is none
+func (c *Client) Timeout() time.Duration {
+	return c.timeout
+}
+
+// Research sends a query and parses the answer into a summary and key points
+func (c *Client) Research(ctx context.Context, query string) (FormattedResponse, error) {
+	redaction := c.redact(query)
+	client, route := c.resolve(ctx, redaction.Text)
+	var content, warning string
+	var usage *Usage
+	client, err := client.fallback(route, nil, func(client *Client) error {
+		var sent string
+		var err error
+		sent, warning = client.fit(redaction.query())
+		content, usage, err = client.complete(ctx, sent)
+		return err
+	})
+	if err != nil {
+		return FormattedResponse{}, err
+	}
+	result := redaction.RestoreResponse(client.format(content))
+	result.Usage = usage
+	result.Route = route
+	if warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+	return result, nil
+}
+
+// ResearchStream is like Research but passes each piece of the answer to onChunk as it
+// arrives. Chunks are unparsed and include the reasoning of thinking models.
+func (c *Client) ResearchStream(ctx context.Context, query string, onChunk func(string)) (FormattedResponse, error) {
+	redaction := c.redact(query)
+	client, route := c.resolve(ctx, redaction.Text)
+	var content, warning string
+	var usage *Usage
+	write, flush := redaction.restoreStream(onChunk)
+	client, err := client.fallback(route, &content, func(client *Client) error {
+		var sent string
+		var err error
+		sent, warning = client.fit(redaction.query())
+		content, usage, err = client.completeStream(ctx, sent, write)
+		return err
+	})
+	flush()
+	if err != nil {
+		return FormattedResponse{}, err
+	}
+	result := redaction.RestoreResponse(client.format(content))
+	result.Usage = usage
+	result.Route = route
+	if warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+	return result, nil
+}
+
+// Complete sends a query and returns the model's answer without parsing it
+func (c *Client) Complete(ctx context.Context, query string) (string, error) {
+	redaction := c.redact(query)
+	client, route := c.resolve(ctx, redaction.Text)
+	var content string
+	_, err := client.fallback(route, nil, func(client *Client) error {
+		var err error
+		sent, _ := client.fit(redaction.query())
+		content, _, err = client.complete(ctx, sent)
+		return err
+	})
+	return redaction.Restore(content), err
+}
+
+// complete sends a query and returns the unparsed answer with the tokens it used
+func (c *Client) complete(ctx context.Context, query string) (string, *Usage, error) {
+	payload, err := c.chatPayload(query)
+	if err != nil {
+		return "", nil, err
+	}
+	return c.chat(ctx, payload)
+}
+
+// chat sends a chat completion request and returns the unparsed answer with the tokens it used
+func (c *Client) chat(ctx context.Context, payload map[string]interface{}) (string, *Usage, error) {
+	jsonBody, _ := json.Marshal(payload)
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	body, resp, err := c.send(ctx, "POST", "/chat/completions", jsonBody)
+	if err != nil {
+		return "", nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, statusError(resp, body)
+	}
+
+	var response struct {
+		APIResponse
+		apiError
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", nil, fmt.Errorf("unexpected response from API: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		// Some providers report failures in a 200 response
+		if response.Error.Message != "" {
+			return "", nil, &StatusError{Status: resp.StatusCode, Message: response.Error.Message}
+		}
+		return "", nil, errors.New("unexpected response from API: no choices")
+	}
+
+	content := response.Choices[0].Message.Content
+	c.logger.Debug("model output", "model", payload["model"], "content", content)
+	return content, response.Usage, nil
+}
+
+// classifyTimeout bounds the call that asks the classifier model for a query's category
+const classifyTimeout = 10 * time.Second
+
+// resolve returns the client to send a query with: the client itself, or for the auto model
+// a copy that uses the model picked for the query, together with why it was picked
+func (c *Client) resolve(ctx context.Context, query string) (*Client, *Route) {
+	if c.model != AutoModel {
+		return c, nil
+	}
+	route, ok := c.router.match(query)
+	if !ok && c.router.classifier != "" {
+		category, err := c.classify(ctx, query)
+		if err == nil {
+			route, ok = c.router.route(category, "classified by "+c.router.classifier, c.lang), true
+		} else {
+			c.logger.Debug("classifier failed, using heuristics", "model", c.router.classifier, "error", err)
+		}
+	}
+	if !ok {
+		route = c.router.Route(query, c.lang)
+	}
+	c.logger.Debug("auto model", "model", route.Model, "category", route.Category, "reason", route.Reason)
+
+	client := *c
+	client.model = route.Model
+	return &client, &route
+}
+
+// fallback sends a query with send. For the auto model, while the API reports the routed model
+// rate limited or failing, it sends to the next model that qualifies for the query's category
+// instead and says so in the route's reason. A streamed answer is never restarted once part of
+// it has been passed on. It returns the client that sent the query last.
+func (c *Client) fallback(route *Route, streamed *string, send func(*Client) error) (*Client, error) {
+	err := send(c)
+	if route == nil {
+		return c, err
+	}
+	for _, model := range c.router.Fallbacks(*route) {
+		var status *StatusError
+		if !errors.As(err, &status) || !status.Temporary() || (streamed != nil && *streamed != "") {
+			break
+		}
+		c.logger.Debug("routed model unavailable, falling back", "model", c.model, "fallback", model, "error", err)
+		route.Reason += fmt.Sprintf("; %s was unavailable (%s), so %s answered", c.model, status, model)
+		route.Model = model
+
+		client := *c
+		client.model = model
+		c = &client
+		err = send(c)
+	}
+	return c, err
+}
+
+// classify asks the router's classifier model for the category of a query
+func (c *Client) classify(ctx context.Context, query string) (string, error) {
+	model, err := GetModel(c.router.classifier)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, classifyTimeout)
+	defer cancel()
+
+	answer, _, err := c.chat(ctx, map[string]interface{}{
+		"model": model.APIName,
+		"messages": []map[string]string{
+			{"role": "system", "content": classifierPrompt},
+			{"role": "user", "content": query},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if model.IsThinking {
+		answer = processThinkingModelResponse(answer)
+	}
+	category, ok := parseCategory(answer)
+	if !ok {
+		return "", fmt.Errorf("unexpected category '%s'", strings.TrimSpace(answer))
+	}
+	return category, nil
+}
+
+// streamChunk is one server-sent event of a streamed chat completion
+type streamChunk struct {
+	apiError
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+// CompleteStream is like Complete but passes each piece of the answer to onChunk as it arrives
+func (c *Client) CompleteStream(ctx context.Context, query string, onChunk func(string)) (string, error) {
+	redaction := c.redact(query)
+	client, route := c.resolve(ctx, redaction.Text)
+	var content string
+	write, flush := redaction.restoreStream(onChunk)
+	_, err := client.fallback(route, &content, func(client *Client) error {
+		var err error
+		sent, _ := client.fit(redaction.query())
+		content, _, err = client.completeStream(ctx, sent, write)
+		return err
+	})
+	flush()
+	return redaction.Restore(content), err
+}
+
+// Redact returns the query as it would be sent, with the values the client's redactor
+// replaced. Without a redactor the query is unchanged.
+func (c *Client) Redact(query string) Redaction {
+	return c.redactor.Redact(query)
+}
+
+// redact redacts a query that is about to be sent
+func (c *Client) redact(query string) Redaction {
+	redaction := c.redactor.Redact(query)
+	if len(redaction.Values) > 0 {
+		c.logger.Debug("redacted query", "rules", redaction.Rules(), "values", len(redaction.Values))
+	}
+	return redaction
+}
+
+func (c *Client) completeStream(ctx context.Context, query string, onChunk func(string)) (string, *Usage, error) {
+	payload, err := c.chatPayload(query)
+	if err != nil {
+		return "", nil, err
+	}
+	payload["stream"] = true
+	jsonBody, _ := json.Marshal(payload)
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, "POST", "/chat/completions", jsonBody)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		c.logger.Debug("api response body", headerAttrs(resp.Header), "body", string(body))
+		return "", nil, statusError(resp, body)
+	}
+
+	var content strings.Builder
+	var usage *Usage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// Blank lines, comments such as keep-alives and other event fields are skipped
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return content.String(), nil, fmt.Errorf("unexpected stream event from API: %w", err)
+		}
+		if chunk.Error.Message != "" {
+			return content.String(), nil, &StatusError{Status: resp.StatusCode, Message: chunk.Error.Message}
+		}
+		// The final event carries the token count of the whole answer
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if delta := choice.Delta.Content; delta != "" {
+				content.WriteString(delta)
+				if onChunk != nil {
+					onChunk(delta)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return content.String(), nil, err
+	}
+
+	c.logger.Debug("model output", "model", c.model, "content", content.String())
+	return content.String(), usage, nil
+}
+
+// Ping sends a minimal one-token request to a model and measures the round trip
+func (c *Client) Ping(ctx context.Context, modelID string) PingResult {
+	result := PingResult{ModelID: modelID}
+
+	model, err := GetModel(modelID)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"model":      model.APIName,
+		"max_tokens": 1,
+		"messages": []map[string]string{
+			{"role": "user", "content": "ping"},
+		},
+	})
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	body, resp, err := c.send(ctx, "POST", "/chat/completions", payload)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		result.Err = statusError(resp, body)
+		return result
+	}
+
+	result.Available = true
+	return result
+}
+
+// PingAll probes the given models concurrently and returns results in the same order
+func (c *Client) PingAll(ctx context.Context, modelIDs []string) []PingResult {
+	results := make([]PingResult, len(modelIDs))
+
+	var wg sync.WaitGroup
+	for i, id := range modelIDs {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			results[i] = c.Ping(ctx, id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// CheckKey asks the provider's key endpoint whether the client's API key is valid
+func (c *Client) CheckKey(ctx context.Context) (*KeyInfo, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	body, resp, err := c.send(ctx, "GET", "/key", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, body)
+	}
+
+	var response struct {
+		Data KeyInfo `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("unexpected response from key endpoint: %w", err)
+	}
+	return &response.Data, nil
+}
+
+// Prompts returns the system and user prompts a query is sent as, after redaction and
+// after trimming what does not fit in the model's context window
+func (c *Client) Prompts(query string) (string, string, error) {
+	redaction := c.Redact(query)
+	model, err := c.previewModel(redaction.Text)
+	if err != nil {
+		return "", "", err
+	}
+	template := c.templates[c.template].Localize(c.lang)
+	sent, _ := fitQuery(template, *model, redaction.query())
+	system, user := template.BuildPrompts(sent, *model)
+	return system, user, nil
+}
+
+// ContextWarning says how a query will be trimmed to fit in the model's context window, or
+// returns "" if it fits
+func (c *Client) ContextWarning(query string) string {
+	redaction := c.Redact(query)
+	model, err := c.previewModel(redaction.Text)
+	if err != nil {
+		return ""
+	}
+	_, warning := fitQuery(c.templates[c.template].Localize(c.lang), *model, redaction.query())
+	return warning
+}
+
+// previewModel returns the model a redacted query would be sent to. Previews never call the
+// classifier model, so for the auto model they only use the rules and heuristics.
+func (c *Client) previewModel(query string) (*Model, error) {
+	modelID := c.model
+	if modelID == AutoModel {
+		modelID = c.router.Route(query, c.lang).Model
+	}
+	model, err := GetModel(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model: %w", err)
+	}
+	return model, nil
+}
+
+// fit trims the oldest part of a query that does not fit in the model's context window,
+// returning the query to send and a warning when it trimmed
+func (c *Client) fit(query string) (string, string) {
+	model, err := GetModel(c.model)
+	if err != nil {
+		return query, ""
+	}
+	sent, warning := fitQuery(c.templates[c.template].Localize(c.lang), *model, query)
+	if warning != "" {
+		c.logger.Warn("query truncated to fit the context window", "model", model.ID, "context_len", model.ContextLen,
+			"tokens_before", EstimateTokens(query), "tokens_after", EstimateTokens(sent))
+	}
+	return sent, warning
+}
+
+// chatPayload builds the chat completion request body for a query
+func (c *Client) chatPayload(query string) (map[string]interface{}, error) {
+	model, err := GetModel(c.model)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model: %w", err)
+	}
+	template := c.templates[c.template].Localize(c.lang)
+
+	// Create prompts based on the template and model capabilities
+	systemPrompt, userPrompt := template.BuildPrompts(query, *model)
+
+	payload := map[string]interface{}{
+		"model": model.APIName,
+		"messages": []map[string]string{
+			{"role": "system", "content": systemPrompt},
+			{"role": "user", "content": userPrompt},
+		},
+	}
+	// Leave the answer what the prompt leaves of the context window
+	budget := NewContextBudget(*model, systemPrompt, userPrompt)
+	if budget.MaxTokens > 0 {
+		payload["max_tokens"] = budget.MaxTokens
+	}
+	c.logger.Debug("context budget", "model", model.ID, "context_len", budget.ContextLen, "prompt_tokens", budget.Prompt, "max_tokens", budget.MaxTokens)
+	return payload, nil
+}
+
+// format parses model output, dropping the reasoning of thinking models first
+func (c *Client) format(content string) FormattedResponse {
+	if model, _ := GetModel(c.model); model != nil && model.IsThinking {
+		before := len(content)
+		content = processThinkingModelResponse(content)
+		c.logger.Debug("parser: removed thinking sections", "model", model.ID, "chars_before", before, "chars_after", len(content))
+	}
+	return parseResponse(content, c.logger)
+}
+
+// withTimeout applies the client's timeout to a context
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// send sends an authenticated request to an API path below the base URL and returns
+// the raw response body along with the response, whose body is already closed
+func (c *Client) send(ctx context.Context, method string, path string, body []byte) ([]byte, *http.Response, error) {
+	resp, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	c.logger.Debug("api response body", headerAttrs(resp.Header), "body", string(respBody))
+	if err != nil {
+		c.logger.Warn("reading api response failed", "error", err)
+	}
+	return respBody, resp, nil
+}
+
+// do sends an authenticated request and returns the response with its body unread
+func (c *Client) do(ctx context.Context, method string, path string, body []byte) (*http.Response, error) {
+	// Preview clients may have no key, and must never send a query without one
+	if c.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	endpoint := c.baseURL + path
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", UserAgent())
+	// OpenRouter attributes requests to an app by these headers
+	req.Header.Set("HTTP-Referer", "https://github.com/Jacky040124/photon")
+	req.Header.Set("X-Title", "Photon")
+
+	log := c.logger.With("method", method, "url", endpoint)
+	log.Debug("api request", headerAttrs(req.Header), "payload", string(body))
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Warn("api request failed", "duration", time.Since(start), "error", err)
+		return nil, err
+	}
+	log.Info("api response", "status", resp.StatusCode, "duration", time.Since(start))
+	return resp, nil
+}
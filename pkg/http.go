@@ -0,0 +1,130 @@
+package pkg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Default connection limits for API requests
+const (
+	DefaultConnectTimeout = 10 * time.Second
+	DefaultReadTimeout    = 60 * time.Second
+)
+
+// HTTPConfig controls how photon connects to the API. Zero values use defaults.
+type HTTPConfig struct {
+	// Proxy is an explicit proxy URL; when empty HTTPS_PROXY, HTTP_PROXY and NO_PROXY apply
+	Proxy string
+	// CAFile is a PEM bundle trusted in addition to the system roots
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key for mutual TLS
+	CertFile string
+	KeyFile  string
+	// ConnectTimeout bounds dialing and the TLS handshake
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds the wait for response headers once a request is sent
+	ReadTimeout time.Duration
+}
+
+// mustHTTPClient builds a client for a config that cannot fail, such as the default one
+func mustHTTPClient(config HTTPConfig) *http.Client {
+	client, err := NewHTTPClient(config)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// NewHTTPClient builds an HTTP client from the given config. The client keeps
+// connections alive so repeated requests reuse them.
+func NewHTTPClient(config HTTPConfig) (*http.Client, error) {
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = DefaultConnectTimeout
+	}
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = DefaultReadTimeout
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL '%s'", config.Proxy)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{Timeout: config.ConnectTimeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   config.ConnectTimeout,
+		ResponseHeaderTimeout: config.ReadTimeout,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// newTLSConfig adds the custom CA bundle and client certificate to the TLS settings
+func newTLSConfig(config HTTPConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		if config.CertFile == "" || config.KeyFile == "" {
+			return nil, fmt.Errorf("a client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// ProxyFor returns the proxy a client built from this config uses for a URL, or nil for
+// a direct connection
+func (c HTTPConfig) ProxyFor(target *url.URL) (*url.URL, error) {
+	if c.Proxy != "" {
+		return url.Parse(c.Proxy)
+	}
+	return http.ProxyFromEnvironment(&http.Request{URL: target})
+}
+
+// UserAgent identifies photon and its version to the API
+func UserAgent() string {
+	return fmt.Sprintf("photon/%s (%s/%s)", GetVersion(), runtime.GOOS, runtime.GOARCH)
+}
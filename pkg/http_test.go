@@ -0,0 +1,227 @@
+package pkg
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePEM writes a single PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newClientCert creates a self-signed client certificate and writes it and its key to dir
+func newClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "photon test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, writePEM(t, dir, "client.pem", "CERTIFICATE", der), writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+// serverCAFile writes the certificate of a TLS test server as a CA bundle
+func serverCAFile(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	return writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+}
+
+func TestHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	client, err := NewHTTPClient(HTTPConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("request to a server with an untrusted certificate succeeded")
+	}
+
+	client, err = NewHTTPClient(HTTPConfig{CAFile: serverCAFile(t, server)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with the server's CA bundle failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestHTTPClientCABundleErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		caFile string
+		want   string
+	}{
+		{"missing file", filepath.Join(dir, "missing.pem"), "reading CA bundle"},
+		{"no certificates", empty, "no PEM certificates found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPClient(HTTPConfig{CAFile: tt.caFile})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	cert, certFile, keyFile := newClientCert(t, dir)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "photon test client" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	// Handshakes are expected to fail here, so keep the server from logging them
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	caFile := serverCAFile(t, server)
+
+	client, err := NewHTTPClient(HTTPConfig{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("request without a client certificate succeeded")
+	}
+
+	client, err = NewHTTPClient(HTTPConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with a client certificate failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want 200", resp.StatusCode)
+	}
+
+	if _, err := NewHTTPClient(HTTPConfig{CertFile: certFile}); err == nil {
+		t.Fatal("a client certificate without a key was accepted")
+	}
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(HTTPConfig{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get("http://api.example.invalid/v1/models")
+	if err != nil {
+		t.Fatalf("request through the proxy failed: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://api.example.invalid/v1/models" {
+		t.Fatalf("proxy got %q, want the absolute target URL", proxied)
+	}
+
+	target, _ := url.Parse("https://openrouter.ai/api/v1")
+	got, err := HTTPConfig{Proxy: proxy.URL}.ProxyFor(target)
+	if err != nil || got == nil || got.String() != proxy.URL {
+		t.Fatalf("ProxyFor returned %v, %v, want %s", got, err, proxy.URL)
+	}
+
+	if _, err := NewHTTPClient(HTTPConfig{Proxy: "not a url"}); err == nil {
+		t.Fatal("an invalid proxy URL was accepted")
+	}
+}
+
+func TestClientUserAgent(t *testing.T) {
+	var userAgent, authorization string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, authorization = r.UserAgent(), r.Header.Get("Authorization")
+		w.Write([]byte(`{"data": {"label": "test"}}`))
+	}))
+	defer server.Close()
+
+	httpClient, err := NewHTTPClient(HTTPConfig{CAFile: serverCAFile(t, server)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(WithAPIKey("sk-test"), WithBaseURL(server.URL), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := client.CheckKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Label != "test" {
+		t.Errorf("got label %q, want test", info.Label)
+	}
+	if userAgent != UserAgent() || !strings.HasPrefix(userAgent, "photon/") {
+		t.Errorf("got User-Agent %q, want %q", userAgent, UserAgent())
+	}
+	if authorization != "Bearer sk-test" {
+		t.Errorf("got Authorization %q, want Bearer sk-test", authorization)
+	}
+}
@@ -0,0 +1,217 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Locale holds the text photon shows, and asks models to answer in, for one language
+type Locale struct {
+	Code string
+	Name string
+	// Instruction is added to the system prompt of templates without a translation
+	Instruction string
+
+	// Result view
+	ResultsTitle string
+	Summary      string
+	KeyPoints    string
+	Model        string
+	Thinking     string
+	Lost         string
+	LostHint     string
+	ResultHelp   string
+	FollowUp     string
+	SavedTo      string
+	Copied       string
+	NothingCopy  string
+	NoKeyPoint   string
+	KeyPoint     string
+	SummaryLabel string
+	AutoRoute    string
+
+	// Headings of Markdown output
+	SummaryHeading   string
+	KeyPointsHeading string
+	SourcesHeading   string
+
+	// Model selector
+	SelectModel       string
+	Current           string
+	Testing           string
+	NoModels          string
+	Page              string
+	Details           string
+	SearchPlaceholder string
+	SelectorHelp      string
+	SelectorFooter    string
+	SearchFooter      string
+	HelpHint          string
+	ThinkingFilter    string
+	MultimodalFilter  string
+	ProviderFilter    string
+	SortFilter        string
+	ModelCount        string
+}
+
+// DefaultLanguage is the language used when none is configured
+const DefaultLanguage = "en"
+
+// locales are the languages photon speaks, by code
+var locales = map[string]Locale{
+	"en": {
+		Code: "en",
+		Name: "English",
+
+		ResultsTitle: "PHOTON RESEARCH RESULTS",
+		Summary:      "SUMMARY:",
+		KeyPoints:    "KEY POINTS:",
+		Model:        "Model",
+		Thinking:     "THINKING..",
+		Lost:         "Lost in the tunnel of knowledge. Please try again later.",
+		LostHint:     "Run 'ptn doctor' to find out what went wrong.",
+		ResultHelp:   "↑/↓ scroll • c copy summary • 1-9 copy point • m model • f follow-up • s save • q quit",
+		FollowUp:     "Follow-up: ",
+		SavedTo:      "Saved to ",
+		Copied:       "Copied %s",
+		NothingCopy:  "Nothing to copy for %s",
+		NoKeyPoint:   "No key point %d",
+		KeyPoint:     "key point %d",
+		SummaryLabel: "summary",
+		AutoRoute:    "auto picked %s: %s",
+
+		SummaryHeading:   "Summary",
+		KeyPointsHeading: "Key Points",
+		SourcesHeading:   "Sources",
+
+		SelectModel:       "Select AI Model:",
+		Current:           "(current)",
+		Testing:           "… testing",
+		NoModels:          "No models match. Press c to clear filters.",
+		Page:              "Page %d/%d",
+		Details:           "Provider: %s | Context: %d tokens | Features: %s",
+		SearchPlaceholder: "name, provider or feature",
+		SelectorHelp: "Controls:\n" +
+			"↑/k: Move up      ↓/j: Move down\n" +
+			"←/h: Prev page    →/l: Next page\n" +
+			"Enter: Select     Space: Toggle details\n" +
+			"/: Search         s: Cycle sort\n" +
+			"t: Thinking only  i: Multimodal only\n" +
+			"p: Cycle provider c: Clear filters\n" +
+			"r: Test model\n" +
+			"q/Esc: Quit       ?: Toggle help",
+		SelectorFooter:   "Press Enter to select, / to search, q to quit",
+		SearchFooter:     "Type to filter, Enter or Esc to finish searching",
+		HelpHint:         ", ? for help",
+		ThinkingFilter:   "thinking",
+		MultimodalFilter: "multimodal",
+		ProviderFilter:   "provider: %s",
+		SortFilter:       "sort: %s",
+		ModelCount:       "%d/%d models",
+	},
+	"zh": {
+		Code:        "zh",
+		Name:        "简体中文",
+		Instruction: "请用简体中文回答，并使用'摘要：'和'要点：'作为小节标题。",
+
+		ResultsTitle: "PHOTON 研究结果",
+		Summary:      "摘要：",
+		KeyPoints:    "要点：",
+		Model:        "模型",
+		Thinking:     "思考中..",
+		Lost:         "迷失在知识的隧道中，请稍后再试。",
+		LostHint:     "运行 'ptn doctor' 查看问题所在。",
+		ResultHelp:   "↑/↓ 滚动 • c 复制摘要 • 1-9 复制要点 • m 换模型 • f 追问 • s 保存 • q 退出",
+		FollowUp:     "追问：",
+		SavedTo:      "已保存到 ",
+		Copied:       "已复制%s",
+		NothingCopy:  "%s为空，没有可复制的内容",
+		NoKeyPoint:   "没有第 %d 条要点",
+		KeyPoint:     "第 %d 条要点",
+		SummaryLabel: "摘要",
+		AutoRoute:    "自动选择了 %s：%s",
+
+		SummaryHeading:   "摘要",
+		KeyPointsHeading: "要点",
+		SourcesHeading:   "来源",
+
+		SelectModel:       "选择 AI 模型：",
+		Current:           "（当前）",
+		Testing:           "… 测试中",
+		NoModels:          "没有匹配的模型。按 c 清除筛选。",
+		Page:              "第 %d/%d 页",
+		Details:           "提供方：%s | 上下文：%d tokens | 特性：%s",
+		SearchPlaceholder: "名称、提供方或特性",
+		SelectorHelp: "操作：\n" +
+			"↑/k：上移        ↓/j：下移\n" +
+			"←/h：上一页      →/l：下一页\n" +
+			"Enter：选择      Space：显示详情\n" +
+			"/：搜索          s：切换排序\n" +
+			"t：仅推理模型    i：仅多模态\n" +
+			"p：切换提供方    c：清除筛选\n" +
+			"r：测试模型\n" +
+			"q/Esc：退出      ?：显示帮助",
+		SelectorFooter:   "按 Enter 选择，/ 搜索，q 退出",
+		SearchFooter:     "输入以筛选，按 Enter 或 Esc 结束搜索",
+		HelpHint:         "，? 查看帮助",
+		ThinkingFilter:   "推理",
+		MultimodalFilter: "多模态",
+		ProviderFilter:   "提供方：%s",
+		SortFilter:       "排序：%s",
+		ModelCount:       "%d/%d 个模型",
+	},
+}
+
+// activeLocale is the language of the UI strings
+var activeLocale = locales[DefaultLanguage]
+
+// GetLanguages returns the codes of the supported languages, sorted
+func GetLanguages() []string {
+	var codes []string
+	for code := range locales {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// NormalizeLanguage maps a language or locale name such as "zh-CN" or "zh_CN.UTF-8" to a
+// supported language code, reporting false if the language is not supported
+func NormalizeLanguage(name string) (string, bool) {
+	code := strings.ToLower(strings.TrimSpace(name))
+	if i := strings.IndexAny(code, ".@"); i >= 0 {
+		code = code[:i]
+	}
+	code = strings.ReplaceAll(code, "_", "-")
+	if _, ok := locales[code]; ok {
+		return code, true
+	}
+	if i := strings.IndexByte(code, '-'); i > 0 {
+		if _, ok := locales[code[:i]]; ok {
+			return code[:i], true
+		}
+	}
+	return "", false
+}
+
+// GetLocale returns the locale for a language code, or an error listing the supported ones
+func GetLocale(name string) (Locale, error) {
+	code, ok := NormalizeLanguage(name)
+	if !ok {
+		return Locale{}, fmt.Errorf("language '%s' not supported, expected one of: %s", name, strings.Join(GetLanguages(), ", "))
+	}
+	return locales[code], nil
+}
+
+// SetLanguage makes a language the one UI strings are shown in; unsupported languages are ignored
+func SetLanguage(name string) {
+	if locale, err := GetLocale(name); err == nil {
+		activeLocale = locale
+	}
+}
+
+// CurrentLocale returns the locale UI strings are shown in
+func CurrentLocale() Locale {
+	return activeLocale
+}
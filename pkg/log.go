@@ -0,0 +1,43 @@
+package pkg
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// redactedHeaders holds headers whose values are credentials and must never be logged
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// headerAttrs groups HTTP headers into a log attribute, hiding credential values
+func headerAttrs(header http.Header) slog.Attr {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]any, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header.Values(name), ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			value = redactCredential(value)
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// redactCredential keeps the scheme of an Authorization value, such as "Bearer", and hides the rest
+func redactCredential(value string) string {
+	if scheme, _, ok := strings.Cut(value, " "); ok {
+		return scheme + " [REDACTED]"
+	}
+	return "[REDACTED]"
+}
@@ -0,0 +1,358 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Styles for Markdown elements
+var (
+	mdHeading = textStyle{color: accentColor, bold: true}.render
+	mdBold    = textStyle{bold: true}.render
+	mdItalic  = textStyle{italic: true}.render
+	mdCode    = textStyle{color: codeColor}.render
+	mdLink    = textStyle{color: infoColor, underline: true}.render
+	mdQuote   = textStyle{color: mutedColor, italic: true}.render
+	mdRule    = textStyle{color: faintColor}.render
+
+	codeKeyword = textStyle{color: keywordColor, bold: true}.render
+	codeString  = textStyle{color: stringColor}.render
+	codeNumber  = textStyle{color: numberColor}.render
+	codeComment = textStyle{color: commentColor, italic: true}.render
+	codeLang    = textStyle{color: faintColor}.render
+)
+
+var (
+	orderedItemPattern = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	bulletItemPattern  = regexp.MustCompile(`^(\s*)[-*+•]\s+(.*)$`)
+	headingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	rulePattern        = regexp.MustCompile(`^(\*{3,}|-{3,}|_{3,})$`)
+	tableRulePattern   = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+
+	inlineCodePattern   = regexp.MustCompile("`([^`]+)`")
+	inlineBoldPattern   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	inlineItalicPattern = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*)\*|(^|[^_\w])_([^_\s][^_]*)_`)
+	inlineLinkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// RenderMarkdown renders Markdown text for the terminal, wrapped to the given width.
+// Colors follow the active theme and are dropped when color is disabled.
+func RenderMarkdown(md string, width int) string {
+	if width < 20 {
+		width = 20
+	}
+
+	var out []string
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			if len(out) > 0 && out[len(out)-1] != "" {
+				out = append(out, "")
+			}
+
+		case isCodeFence(trimmed):
+			fence := trimmed[:3]
+			lang := strings.TrimSpace(strings.TrimLeft(trimmed, "`~"))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			out = append(out, renderCodeBlock(code, lang, width)...)
+
+		case isTableRow(trimmed) && i+1 < len(lines) && tableRulePattern.MatchString(strings.TrimSpace(lines[i+1])):
+			rows := [][]string{splitTableRow(trimmed)}
+			for i += 2; i < len(lines) && isTableRow(strings.TrimSpace(lines[i])); i++ {
+				rows = append(rows, splitTableRow(strings.TrimSpace(lines[i])))
+			}
+			i--
+			out = append(out, renderTable(rows, width)...)
+
+		case headingPattern.MatchString(trimmed):
+			match := headingPattern.FindStringSubmatch(trimmed)
+			out = append(out, wrapWithIndent(mdHeading(ansi.Strip(renderInline(match[2]))), width, "", ""))
+
+		case rulePattern.MatchString(trimmed):
+			out = append(out, mdRule(strings.Repeat("─", min(width, 40))))
+
+		case strings.HasPrefix(trimmed, ">"):
+			text := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			out = append(out, wrapWithIndent(mdQuote(ansi.Strip(renderInline(text))), width, "│ ", "│ "))
+
+		case orderedItemPattern.MatchString(line):
+			match := orderedItemPattern.FindStringSubmatch(line)
+			indent := listIndent(match[1])
+			marker := match[2] + ". "
+			out = append(out, wrapWithIndent(renderInline(match[3]), width,
+				indent+marker, indent+strings.Repeat(" ", len(marker))))
+
+		case bulletItemPattern.MatchString(line):
+			match := bulletItemPattern.FindStringSubmatch(line)
+			indent := listIndent(match[1])
+			out = append(out, wrapWithIndent(renderInline(match[2]), width, indent+"• ", indent+"  "))
+
+		default:
+			out = append(out, wrapWithIndent(renderInline(trimmed), width, "", ""))
+		}
+	}
+
+	return strings.TrimRight(strings.Join(out, "\n"), "\n")
+}
+
+// listIndent converts source indentation of a nested list item into display indentation
+func listIndent(leading string) string {
+	depth := len(strings.ReplaceAll(leading, "\t", "    ")) / 2
+	return strings.Repeat("  ", depth)
+}
+
+// wrapWithIndent wraps text to width, using first for the first line and rest for continuation lines
+func wrapWithIndent(text string, width int, first string, rest string) string {
+	avail := max(width-ansi.StringWidth(first), 10)
+	wrapped := strings.Split(ansi.Wrap(text, avail, ""), "\n")
+	for i := range wrapped {
+		if i == 0 {
+			wrapped[i] = first + wrapped[i]
+		} else {
+			wrapped[i] = rest + wrapped[i]
+		}
+	}
+	return strings.Join(wrapped, "\n")
+}
+
+// renderInline styles inline code, links, bold and italic text
+func renderInline(text string) string {
+	// Protect inline code from the other inline rules
+	var spans []string
+	text = inlineCodePattern.ReplaceAllStringFunc(text, func(s string) string {
+		spans = append(spans, mdCode(inlineCodePattern.FindStringSubmatch(s)[1]))
+		return fmt.Sprintf("\x00%d\x00", len(spans)-1)
+	})
+
+	text = inlineLinkPattern.ReplaceAllStringFunc(text, func(s string) string {
+		match := inlineLinkPattern.FindStringSubmatch(s)
+		return match[1] + " (" + mdLink(match[2]) + ")"
+	})
+	text = inlineBoldPattern.ReplaceAllStringFunc(text, func(s string) string {
+		match := inlineBoldPattern.FindStringSubmatch(s)
+		return mdBold(match[1] + match[2])
+	})
+	text = inlineItalicPattern.ReplaceAllStringFunc(text, func(s string) string {
+		match := inlineItalicPattern.FindStringSubmatch(s)
+		return match[1] + match[3] + mdItalic(match[2]+match[4])
+	})
+
+	for i, span := range spans {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), span, 1)
+	}
+	return text
+}
+
+// isCodeFence reports whether a line opens or closes a fenced code block
+func isCodeFence(line string) bool {
+	return strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~")
+}
+
+// renderCodeBlock renders a fenced code block with a gutter and syntax highlighting
+func renderCodeBlock(code []string, lang string, width int) []string {
+	var out []string
+	if lang != "" {
+		out = append(out, codeLang("  "+lang))
+	}
+
+	gutter := mdRule("  │ ")
+	for _, line := range code {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		line = ansi.Truncate(line, max(width-4, 10), "…")
+		out = append(out, gutter+highlightCode(line, lang))
+	}
+	return out
+}
+
+// Keywords highlighted in code blocks, by language
+var codeKeywords = map[string][]string{
+	"go":         {"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "type", "var", "nil", "true", "false"},
+	"python":     {"and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in", "is", "lambda", "None", "not", "or", "pass", "raise", "return", "True", "False", "try", "while", "with", "yield"},
+	"javascript": {"async", "await", "break", "case", "catch", "class", "const", "continue", "default", "delete", "do", "else", "export", "extends", "finally", "for", "function", "if", "import", "in", "instanceof", "let", "new", "null", "return", "switch", "this", "throw", "try", "typeof", "undefined", "var", "while", "yield", "true", "false"},
+	"rust":       {"as", "async", "await", "break", "const", "continue", "crate", "else", "enum", "extern", "fn", "for", "if", "impl", "in", "let", "loop", "match", "mod", "move", "mut", "pub", "ref", "return", "self", "Self", "static", "struct", "trait", "true", "false", "type", "unsafe", "use", "where", "while"},
+	"shell":      {"if", "then", "else", "elif", "fi", "for", "while", "do", "done", "case", "esac", "in", "function", "return", "export", "local", "echo", "sudo"},
+	"sql":        {"SELECT", "FROM", "WHERE", "INSERT", "INTO", "VALUES", "UPDATE", "SET", "DELETE", "CREATE", "TABLE", "DROP", "ALTER", "JOIN", "LEFT", "RIGHT", "INNER", "OUTER", "ON", "GROUP", "BY", "ORDER", "HAVING", "LIMIT", "AND", "OR", "NOT", "NULL", "AS", "DISTINCT", "INDEX"},
+}
+
+// Aliases for fenced code block languages
+var codeLangAliases = map[string]string{
+	"golang": "go", "py": "python", "js": "javascript", "ts": "javascript", "typescript": "javascript",
+	"jsx": "javascript", "tsx": "javascript", "rs": "rust", "sh": "shell", "bash": "shell", "zsh": "shell",
+	"console": "shell",
+}
+
+// commentPrefix returns the line comment marker for a language
+func commentPrefix(lang string) string {
+	switch lang {
+	case "python", "shell", "ruby", "yaml", "toml":
+		return "#"
+	case "sql", "lua", "haskell":
+		return "--"
+	default:
+		return "//"
+	}
+}
+
+// highlightCode applies simple token-based syntax highlighting to a line of code
+func highlightCode(line string, lang string) string {
+	if !ColorEnabled() {
+		return line
+	}
+
+	lang = strings.ToLower(lang)
+	if alias, ok := codeLangAliases[lang]; ok {
+		lang = alias
+	}
+	keywords := make(map[string]bool)
+	for _, kw := range codeKeywords[lang] {
+		keywords[kw] = true
+		if lang == "sql" {
+			keywords[strings.ToLower(kw)] = true
+		}
+	}
+	comment := commentPrefix(lang)
+
+	var b strings.Builder
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case strings.HasPrefix(string(runes[i:]), comment):
+			b.WriteString(codeComment(string(runes[i:])))
+			return b.String()
+
+		case r == '"' || r == '\'' || r == '`':
+			j := i + 1
+			for j < len(runes) && runes[j] != r {
+				if runes[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(runes))
+			b.WriteString(codeString(string(runes[i:j])))
+			i = j
+
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.' || runes[j] == '_' || unicode.IsLetter(runes[j])) {
+				j++
+			}
+			b.WriteString(codeNumber(string(runes[i:j])))
+			i = j
+
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			word := string(runes[i:j])
+			if keywords[word] {
+				word = codeKeyword(word)
+			}
+			b.WriteString(word)
+			i = j
+
+		default:
+			b.WriteRune(r)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// isTableRow reports whether a line looks like a Markdown table row
+func isTableRow(line string) bool {
+	return strings.HasPrefix(line, "|") && strings.Count(line, "|") >= 2
+}
+
+// splitTableRow splits a Markdown table row into trimmed cells
+func splitTableRow(line string) []string {
+	line = strings.TrimPrefix(strings.TrimSuffix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = renderInline(strings.TrimSpace(cells[i]))
+	}
+	return cells
+}
+
+// renderTable renders table rows with box-drawing borders, shrinking columns to fit the width
+func renderTable(rows [][]string, width int) []string {
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+
+	widths := make([]int, cols)
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], ansi.StringWidth(cell))
+		}
+	}
+
+	// Shrink the widest column until the table fits: borders take 3 cells per column plus 1
+	for total(widths)+3*cols+1 > width {
+		widest := 0
+		for i := range widths {
+			if widths[i] > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= 3 {
+			break
+		}
+		widths[widest]--
+	}
+
+	border := func(left, mid, right string) string {
+		parts := make([]string, cols)
+		for i, w := range widths {
+			parts[i] = strings.Repeat("─", w+2)
+		}
+		return mdRule(left + strings.Join(parts, mid) + right)
+	}
+
+	out := []string{border("┌", "┬", "┐")}
+	for r, row := range rows {
+		var b strings.Builder
+		b.WriteString(mdRule("│"))
+		for i, w := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = ansi.Truncate(row[i], w, "…")
+			}
+			if r == 0 {
+				cell = mdBold(ansi.Strip(cell))
+			}
+			b.WriteString(" " + cell + strings.Repeat(" ", w-ansi.StringWidth(cell)) + " ")
+			b.WriteString(mdRule("│"))
+		}
+		out = append(out, b.String())
+		if r == 0 {
+			out = append(out, border("├", "┼", "┤"))
+		}
+	}
+	out = append(out, border("└", "┴", "┘"))
+	return out
+}
+
+// total returns the sum of a slice of ints
+func total(values []int) int {
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		md    string
+		width int
+		want  string
+	}{
+		{
+			name:  "paragraph",
+			md:    "Go is a language.",
+			width: 80,
+			want:  "Go is a language.",
+		},
+		{
+			name:  "inline styles are unwrapped",
+			md:    "Go is **compiled**, *fast* and uses `go build`.",
+			width: 80,
+			want:  "Go is compiled, fast and uses go build.",
+		},
+		{
+			name:  "link shows its address",
+			md:    "See [docs](https://go.dev).",
+			width: 80,
+			want:  "See docs (https://go.dev).",
+		},
+		{
+			name:  "inline code keeps markdown characters",
+			md:    "Use `**kwargs` here",
+			width: 80,
+			want:  "Use **kwargs here",
+		},
+		{
+			name:  "heading",
+			md:    "## Install **now**",
+			width: 80,
+			want:  "Install now",
+		},
+		{
+			name:  "bullets and nested items",
+			md:    "- one\n  - two\n* three",
+			width: 80,
+			want:  "• one\n  • two\n• three",
+		},
+		{
+			name:  "ordered items keep their numbers",
+			md:    "1. one\n2) two",
+			width: 80,
+			want:  "1. one\n2. two",
+		},
+		{
+			name:  "blank lines collapse",
+			md:    "one\n\n\n\ntwo",
+			width: 80,
+			want:  "one\n\ntwo",
+		},
+		{
+			name:  "quote",
+			md:    "> careful",
+			width: 80,
+			want:  "│ careful",
+		},
+		{
+			name:  "wrapped list item is indented",
+			md:    "- alpha beta gamma delta epsilon zeta",
+			width: 20,
+			want:  "• alpha beta gamma\n  delta epsilon zeta",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ansi.Strip(RenderMarkdown(tt.md, tt.width)); got != tt.want {
+				t.Errorf("RenderMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		md       string
+		contains []string
+	}{
+		{
+			name:     "code block keeps its lines",
+			md:       "```go\nfunc main() {\n\tfmt.Println(\"**hi**\")\n}\n```",
+			contains: []string{"go", "func main() {", `fmt.Println("**hi**")`},
+		},
+		{
+			name:     "table aligns its cells",
+			md:       "| name | value |\n|---|---|\n| a | 1 |\n| bb | 22 |",
+			contains: []string{"│ name │ value │", "│ a    │ 1     │", "│ bb   │ 22    │"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ansi.Strip(RenderMarkdown(tt.md, 80))
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("RenderMarkdown() = %q, missing %q", got, want)
+				}
+			}
+			for _, line := range strings.Split(got, "\n") {
+				if ansi.StringWidth(line) > 80 {
+					t.Errorf("line %q is wider than 80 columns", line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Sort orders for model lists
+const (
+	SortDefault = iota
+	SortName
+	SortContext
+)
+
+// sortModeNames are the labels shown for each sort order
+var sortModeNames = []string{"default", "name", "context"}
+
+// ModelFilter narrows and orders a list of models
+type ModelFilter struct {
+	Query      string
+	Thinking   bool
+	Multimodal bool
+	Provider   string
+	Sort       int
+}
+
+// Active reports whether the filter hides any model
+func (f ModelFilter) Active() bool {
+	return f.Query != "" || f.Thinking || f.Multimodal || f.Provider != ""
+}
+
+// Apply returns the IDs from order that match the filter, sorted by the filter's sort order.
+// When a query is set and no explicit sort is chosen, the best fuzzy matches come first.
+func (f ModelFilter) Apply(models map[string]Model, order []string) []string {
+	type match struct {
+		id    string
+		score int
+		index int
+	}
+
+	var matches []match
+	for i, id := range order {
+		model, exists := models[id]
+		if !exists {
+			continue
+		}
+		if f.Thinking && !model.IsThinking {
+			continue
+		}
+		if f.Multimodal && !model.IsMultimodal {
+			continue
+		}
+		if f.Provider != "" && !strings.EqualFold(model.Provider, f.Provider) {
+			continue
+		}
+
+		score := 0
+		if f.Query != "" {
+			var ok bool
+			if score, ok = fuzzyMatchModel(f.Query, model); !ok {
+				continue
+			}
+		}
+		matches = append(matches, match{id: id, score: score, index: i})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := models[matches[i].id], models[matches[j].id]
+		switch f.Sort {
+		case SortName:
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		case SortContext:
+			return a.ContextLen > b.ContextLen
+		default:
+			if matches[i].score != matches[j].score {
+				return matches[i].score > matches[j].score
+			}
+			return matches[i].index < matches[j].index
+		}
+	})
+
+	ids := make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = m.id
+	}
+	return ids
+}
+
+// GetProviders returns the distinct providers of the given models, sorted by name
+func GetProviders(models map[string]Model) []string {
+	seen := make(map[string]bool)
+	var providers []string
+	for _, model := range models {
+		if !seen[model.Provider] {
+			seen[model.Provider] = true
+			providers = append(providers, model.Provider)
+		}
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// fuzzyMatchModel scores a query against a model's ID, name, provider and features,
+// returning the best score of any field
+func fuzzyMatchModel(query string, model Model) (int, bool) {
+	fields := append([]string{model.ID, model.Name, model.Provider}, model.Features...)
+
+	best, found := 0, false
+	for _, field := range fields {
+		if score, ok := fuzzyScore(query, field); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+// fuzzyScore matches query as a case-insensitive subsequence of text. Consecutive
+// characters, word starts and matches near the beginning score higher.
+func fuzzyScore(query string, text string) (int, bool) {
+	q := []rune(strings.ToLower(strings.TrimSpace(query)))
+	t := []rune(strings.ToLower(text))
+	if len(q) == 0 {
+		return 0, true
+	}
+
+	score, qi, last := 0, 0, -1
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if t[ti] != q[qi] {
+			continue
+		}
+
+		score++
+		if last == ti-1 {
+			score += 5
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += 3
+		}
+		if qi == 0 {
+			score -= min(ti, 10)
+		}
+		last = ti
+		qi++
+	}
+
+	if qi < len(q) {
+		return 0, false
+	}
+	return score, true
+}
@@ -1,22 +1,34 @@
 package pkg
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 // keyMap defines the keybindings for the model selector
 type keyMap struct {
-	Up       key.Binding
-	Down     key.Binding
-	Select   key.Binding
-	Quit     key.Binding
-	Help     key.Binding
-	Details  key.Binding
+	Up         key.Binding
+	Down       key.Binding
+	PrevPage   key.Binding
+	NextPage   key.Binding
+	Select     key.Binding
+	Quit       key.Binding
+	Help       key.Binding
+	Details    key.Binding
+	Search     key.Binding
+	EndSearch  key.Binding
+	Thinking   key.Binding
+	Multimodal key.Binding
+	Provider   key.Binding
+	Sort       key.Binding
+	ClearAll   key.Binding
+	Ping       key.Binding
 }
 
 var keys = keyMap{
@@ -28,6 +40,14 @@ var keys = keyMap{
 		key.WithKeys("down", "j"),
 		key.WithHelp("↓/j", "move down"),
 	),
+	PrevPage: key.NewBinding(
+		key.WithKeys("left", "h", "pgup"),
+		key.WithHelp("←/h", "previous page"),
+	),
+	NextPage: key.NewBinding(
+		key.WithKeys("right", "l", "pgdown"),
+		key.WithHelp("→/l", "next page"),
+	),
 	Select: key.NewBinding(
 		key.WithKeys("enter"),
 		key.WithHelp("enter", "select"),
@@ -44,43 +64,122 @@ var keys = keyMap{
 		key.WithKeys(" "),
 		key.WithHelp("space", "details"),
 	),
+	Search: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search"),
+	),
+	EndSearch: key.NewBinding(
+		key.WithKeys("esc", "enter"),
+	),
+	Thinking: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "thinking only"),
+	),
+	Multimodal: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "multimodal only"),
+	),
+	Provider: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "cycle provider"),
+	),
+	Sort: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "cycle sort"),
+	),
+	ClearAll: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "clear filters"),
+	),
+	Ping: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "test model"),
+	),
 }
 
 // ModelSelectorModel represents the state of the model selector TUI
 type ModelSelectorModel struct {
 	models       map[string]Model
 	modelOrder   []string
+	visible      []string
+	providers    []string
+	filter       ModelFilter
+	search       textinput.Model
+	searching    bool
 	currentModel string
 	cursor       int
 	selected     string
 	showDetails  bool
 	showHelp     bool
+	pings        map[string]PingResult
+	pinging      map[string]bool
+	client       *Client
 	width        int
 	height       int
+	embedded     bool
+}
+
+// ModelSelectedMsg is emitted by an embedded selector when the user picks a model.
+// An empty ModelID means the selection was cancelled.
+type ModelSelectedMsg struct {
+	ModelID string
+}
+
+// ModelPingMsg carries the result of a model probe started from the selector
+type ModelPingMsg struct {
+	Result PingResult
 }
 
 // NewModelSelector creates a new model selector
 func NewModelSelector(currentModel string) ModelSelectorModel {
 	models := GetAvailableModels()
-	modelOrder := []string{"kimi", "deepseek-r1", "deepseek-v3", "llama-4", "mistral"}
-	
-	// Find current model index for initial cursor position
-	cursor := 0
-	for i, id := range modelOrder {
-		if id == currentModel {
-			cursor = i
-			break
-		}
-	}
+	modelOrder := GetModelOrder()
 
-	return ModelSelectorModel{
+	search := textinput.New()
+	search.Prompt = "/ "
+	search.Placeholder = activeLocale.SearchPlaceholder
+
+	m := ModelSelectorModel{
 		models:       models,
 		modelOrder:   modelOrder,
+		visible:      modelOrder,
+		providers:    GetProviders(models),
+		search:       search,
+		pings:        make(map[string]PingResult),
+		pinging:      make(map[string]bool),
 		currentModel: currentModel,
-		cursor:       cursor,
 		width:        80,
 		height:       20,
 	}
+
+	// Find current model index for initial cursor position
+	m.moveCursorTo(currentModel)
+	return m
+}
+
+// NewEmbeddedModelSelector creates a model selector meant to run inside another
+// program. Instead of quitting, it reports the outcome with a ModelSelectedMsg.
+func NewEmbeddedModelSelector(currentModel string) ModelSelectorModel {
+	m := NewModelSelector(currentModel)
+	m.embedded = true
+	return m
+}
+
+// WithClient sets the client used when testing models from the selector
+func (m ModelSelectorModel) WithClient(client *Client) ModelSelectorModel {
+	m.client = client
+	return m
+}
+
+// done finishes the selection, either by quitting or by notifying the parent program
+func (m ModelSelectorModel) done() tea.Cmd {
+	if !m.embedded {
+		return tea.Quit
+	}
+	selected := m.selected
+	return func() tea.Msg {
+		return ModelSelectedMsg{ModelID: selected}
+	}
 }
 
 // Init initializes the model selector
@@ -96,173 +195,373 @@ func (m ModelSelectorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		return m, nil
 
+	case ModelPingMsg:
+		delete(m.pinging, msg.Result.ModelID)
+		m.pings[msg.Result.ModelID] = msg.Result
+		return m, nil
+
 	case tea.KeyMsg:
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+
 		switch {
 		case key.Matches(msg, keys.Quit):
-			return m, tea.Quit
+			return m, m.done()
 
 		case key.Matches(msg, keys.Up):
 			if m.cursor > 0 {
 				m.cursor--
 			} else {
-				m.cursor = len(m.modelOrder) - 1
+				m.cursor = max(len(m.visible)-1, 0)
 			}
 
 		case key.Matches(msg, keys.Down):
-			if m.cursor < len(m.modelOrder)-1 {
+			if m.cursor < len(m.visible)-1 {
 				m.cursor++
 			} else {
 				m.cursor = 0
 			}
 
+		case key.Matches(msg, keys.PrevPage):
+			m.cursor = max(m.cursor-m.perPage(), 0)
+
+		case key.Matches(msg, keys.NextPage):
+			m.cursor = min(m.cursor+m.perPage(), max(len(m.visible)-1, 0))
+
 		case key.Matches(msg, keys.Select):
-			m.selected = m.modelOrder[m.cursor]
-			return m, tea.Quit
+			if len(m.visible) == 0 {
+				return m, nil
+			}
+			m.selected = m.visible[m.cursor]
+			return m, m.done()
 
 		case key.Matches(msg, keys.Details):
 			m.showDetails = !m.showDetails
 
 		case key.Matches(msg, keys.Help):
 			m.showHelp = !m.showHelp
+
+		case key.Matches(msg, keys.Search):
+			m.searching = true
+			return m, m.search.Focus()
+
+		case key.Matches(msg, keys.Thinking):
+			m.filter.Thinking = !m.filter.Thinking
+			m.applyFilter()
+
+		case key.Matches(msg, keys.Multimodal):
+			m.filter.Multimodal = !m.filter.Multimodal
+			m.applyFilter()
+
+		case key.Matches(msg, keys.Provider):
+			m.filter.Provider = nextProvider(m.providers, m.filter.Provider)
+			m.applyFilter()
+
+		case key.Matches(msg, keys.Sort):
+			m.filter.Sort = (m.filter.Sort + 1) % len(sortModeNames)
+			m.applyFilter()
+
+		case key.Matches(msg, keys.ClearAll):
+			m.filter = ModelFilter{}
+			m.search.SetValue("")
+			m.applyFilter()
+
+		case key.Matches(msg, keys.Ping):
+			if len(m.visible) == 0 || m.pinging[m.visible[m.cursor]] {
+				return m, nil
+			}
+			modelID := m.visible[m.cursor]
+			m.pinging[modelID] = true
+			return m, pingModelCmd(modelID, m.client)
 		}
 	}
 
 	return m, nil
 }
 
+// updateSearch handles key presses while the search box is focused
+func (m ModelSelectorModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.EndSearch):
+		m.searching = false
+		m.search.Blur()
+		return m, nil
+
+	case msg.Type == tea.KeyUp || msg.Type == tea.KeyDown:
+		m.searching = false
+		m.search.Blur()
+		return m.Update(msg)
+	}
+
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	if m.search.Value() != m.filter.Query {
+		m.filter.Query = m.search.Value()
+		m.applyFilter()
+	}
+	return m, cmd
+}
+
+// applyFilter recomputes the visible models, keeping the highlighted model when it still matches
+func (m *ModelSelectorModel) applyFilter() {
+	highlighted := ""
+	if m.cursor < len(m.visible) {
+		highlighted = m.visible[m.cursor]
+	}
+
+	m.visible = m.filter.Apply(m.models, m.modelOrder)
+	m.cursor = 0
+	m.moveCursorTo(highlighted)
+}
+
+// moveCursorTo places the cursor on the given model if it is visible
+func (m *ModelSelectorModel) moveCursorTo(modelID string) {
+	for i, id := range m.visible {
+		if id == modelID {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// pingModelCmd probes a model in the background and reports back with a ModelPingMsg
+func pingModelCmd(modelID string, client *Client) tea.Cmd {
+	return func() tea.Msg {
+		if client == nil {
+			return ModelPingMsg{Result: PingResult{ModelID: modelID, Err: errNoAPIKey}}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultPingTimeout)
+		defer cancel()
+		return ModelPingMsg{Result: client.Ping(ctx, modelID)}
+	}
+}
+
+// nextProvider cycles through providers, returning to no provider filter after the last one
+func nextProvider(providers []string, current string) string {
+	if current == "" {
+		if len(providers) == 0 {
+			return ""
+		}
+		return providers[0]
+	}
+	for i, provider := range providers {
+		if provider == current && i+1 < len(providers) {
+			return providers[i+1]
+		}
+	}
+	return ""
+}
+
+// perPage returns how many models fit on screen. Each model takes three lines
+// and the header, search line and footer take about eight.
+func (m ModelSelectorModel) perPage() int {
+	reserved := 8
+	if m.showDetails {
+		reserved++
+	}
+	if m.showHelp {
+		reserved += 10
+	}
+	return max((m.height-reserved)/3, 1)
+}
+
 // View renders the model selector interface
 func (m ModelSelectorModel) View() string {
 	var b strings.Builder
 
 	// Header
+	theme := CurrentTheme()
 	headerStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("86")).
-		MarginBottom(1)
-	
-	b.WriteString(headerStyle.Render("🤖 Select AI Model:"))
+		Foreground(ThemeColor(theme.Primary))
+	mutedStyle := lipgloss.NewStyle().
+		Foreground(ThemeColor(theme.Muted))
+
+	b.WriteString(headerStyle.Render(Icon("🤖") + activeLocale.SelectModel))
+	b.WriteString("\n")
+
+	// Search box and active filters
+	if m.searching || m.filter.Query != "" {
+		b.WriteString(m.search.View())
+	}
+	if status := m.filterStatus(); status != "" {
+		b.WriteString("  " + mutedStyle.Render(status))
+	}
 	b.WriteString("\n\n")
 
-	// Model list
-	for i, modelID := range m.modelOrder {
+	if len(m.visible) == 0 {
+		b.WriteString(mutedStyle.Render("  " + activeLocale.NoModels))
+		b.WriteString("\n\n")
+	}
+
+	// Current page of the model list
+	perPage := m.perPage()
+	page := m.cursor / perPage
+	pages := max((len(m.visible)+perPage-1)/perPage, 1)
+	start := page * perPage
+	end := min(start+perPage, len(m.visible))
+
+	for i := start; i < end; i++ {
+		modelID := m.visible[i]
 		model := m.models[modelID]
-		
+
 		// Style based on selection and current model
 		var style lipgloss.Style
 		var prefix string
 		var suffix string
-		
+
 		if i == m.cursor {
 			// Highlighted/selected item
 			style = lipgloss.NewStyle().
 				Bold(true).
-				Foreground(lipgloss.Color("15")).
-				Background(lipgloss.Color("63")).
+				Foreground(ThemeColor(theme.SelectionText)).
+				Background(ThemeColor(theme.Selection)).
+				Reverse(theme.Selection == "" || !ColorEnabled()).
 				Padding(0, 1)
 			prefix = ">"
 		} else {
 			// Regular item
 			style = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("15")).
+				Foreground(ThemeColor(theme.Text)).
 				Padding(0, 1)
 			prefix = " "
 		}
-		
+
 		// Mark current model
 		if modelID == m.currentModel {
-			suffix = GreenBold(" (current)")
+			suffix = GreenBold(" " + activeLocale.Current)
 		}
-		
+
+		// Show probe status
+		if m.pinging[modelID] {
+			suffix += " " + Muted(activeLocale.Testing)
+		} else if result, ok := m.pings[modelID]; ok {
+			suffix += " " + FormatPingResult(result)
+		}
+
 		// Model name line
 		modelLine := fmt.Sprintf("%s %s%s", prefix, model.Name, suffix)
 		b.WriteString(style.Render(modelLine))
 		b.WriteString("\n")
-		
+
 		// Description line (always shown, but styled differently for selected)
 		var descStyle lipgloss.Style
 		if i == m.cursor {
 			descStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("245")).
+				Foreground(ThemeColor(theme.Muted)).
 				Italic(true).
 				MarginLeft(2)
 		} else {
 			descStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("240")).
+				Foreground(ThemeColor(theme.Faint)).
 				MarginLeft(2)
 		}
-		
+
 		b.WriteString(descStyle.Render(model.Description))
 		b.WriteString("\n")
-		
+
 		// Show additional details for selected model if requested
+		if result, ok := m.pings[modelID]; ok && result.Err != nil && i == m.cursor {
+			errStyle := lipgloss.NewStyle().
+				Foreground(ThemeColor(theme.Error)).
+				MarginLeft(2)
+			b.WriteString(errStyle.Render(result.Err.Error()))
+			b.WriteString("\n")
+		}
+
 		if i == m.cursor && m.showDetails {
 			detailStyle := lipgloss.NewStyle().
-				Foreground(lipgloss.Color("117")).
+				Foreground(ThemeColor(theme.Info)).
 				MarginLeft(2).
 				Italic(true)
-			
-			details := fmt.Sprintf("Provider: %s | Context: %d tokens | Features: %s",
+
+			details := fmt.Sprintf(activeLocale.Details,
 				model.Provider,
 				model.ContextLen,
 				strings.Join(model.Features, ", "))
-			
+
 			b.WriteString(detailStyle.Render(details))
 			b.WriteString("\n")
 		}
-		
+
+		b.WriteString("\n")
+	}
+
+	if pages > 1 {
+		b.WriteString(mutedStyle.Render("  " + fmt.Sprintf(activeLocale.Page, page+1, pages)))
 		b.WriteString("\n")
 	}
 
 	// Help section
 	if m.showHelp {
 		helpStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("244")).
+			Foreground(ThemeColor(theme.Muted)).
 			Border(lipgloss.RoundedBorder()).
 			Padding(1).
 			MarginTop(1)
-		
-		helpText := "Controls:\n" +
-			"↑/k: Move up    ↓/j: Move down\n" +
-			"Enter: Select   Space: Toggle details\n" +
-			"q/Esc: Quit     ?: Toggle help"
-		
-		b.WriteString(helpStyle.Render(helpText))
+
+		b.WriteString(helpStyle.Render(activeLocale.SelectorHelp))
 		b.WriteString("\n")
 	}
 
 	// Footer
 	footerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("244")).
+		Foreground(ThemeColor(theme.Muted)).
 		MarginTop(1)
-	
-	footerText := "Press Enter to select, q to quit"
-	if !m.showHelp {
-		footerText += ", ? for help"
+
+	footerText := activeLocale.SelectorFooter
+	if m.searching {
+		footerText = activeLocale.SearchFooter
+	} else if !m.showHelp {
+		footerText += activeLocale.HelpHint
 	}
-	
+
 	b.WriteString(footerStyle.Render(footerText))
 
 	return b.String()
 }
 
+// filterStatus describes the active filters and sort order
+func (m ModelSelectorModel) filterStatus() string {
+	var parts []string
+	if m.filter.Thinking {
+		parts = append(parts, activeLocale.ThinkingFilter)
+	}
+	if m.filter.Multimodal {
+		parts = append(parts, activeLocale.MultimodalFilter)
+	}
+	if m.filter.Provider != "" {
+		parts = append(parts, fmt.Sprintf(activeLocale.ProviderFilter, m.filter.Provider))
+	}
+	if m.filter.Sort != SortDefault {
+		parts = append(parts, fmt.Sprintf(activeLocale.SortFilter, sortModeNames[m.filter.Sort]))
+	}
+	if m.filter.Active() {
+		parts = append(parts, fmt.Sprintf(activeLocale.ModelCount, len(m.visible), len(m.modelOrder)))
+	}
+	return strings.Join(parts, " • ")
+}
+
 // GetSelectedModel returns the selected model ID, or empty string if cancelled
 func (m ModelSelectorModel) GetSelectedModel() string {
 	return m.selected
 }
 
-// RunModelSelector runs the interactive model selector and returns the selected model ID
-func RunModelSelector(currentModel string) (string, error) {
-	m := NewModelSelector(currentModel)
+// RunModelSelector runs the interactive model selector and returns the selected model ID.
+// The client is used to test models and may be nil when no API key is configured.
+func RunModelSelector(currentModel string, client *Client) (string, error) {
+	m := NewModelSelector(currentModel).WithClient(client)
 	program := tea.NewProgram(m)
-	
+
 	finalModel, err := program.Run()
 	if err != nil {
 		return "", err
 	}
-	
+
 	if selectorModel, ok := finalModel.(ModelSelectorModel); ok {
 		return selectorModel.GetSelectedModel(), nil
 	}
-	
+
 	return "", nil
-}
\ No newline at end of file
+}
@@ -85,6 +85,11 @@ func GetAvailableModels() map[string]Model {
 	}
 }
 
+// GetModelOrder returns the model IDs in the order they are presented to users
+func GetModelOrder() []string {
+	return []string{"kimi", "deepseek-r1", "deepseek-v3", "llama-4", "mistral"}
+}
+
 // GetDefaultModel returns the default model ID
 func GetDefaultModel() string {
 	return "deepseek-v3"
@@ -120,21 +125,21 @@ func ValidateModel(id string) bool {
 // FormatModelInfo returns a formatted string with model information
 func FormatModelInfo(model Model) string {
 	var b strings.Builder
-	
-	b.WriteString(fmt.Sprintf("%s %s\n", CyanBold("📋 Model:"), YellowBold(model.Name)))
-	b.WriteString(fmt.Sprintf("%s %s\n", BlueBold("🏢 Provider:"), White(model.Provider)))
-	b.WriteString(fmt.Sprintf("%s %s\n", GreenBold("📝 Description:"), White(model.Description)))
-	b.WriteString(fmt.Sprintf("%s %s\n", Magenta("🎯 Best For:"), White(model.BestFor)))
-	b.WriteString(fmt.Sprintf("%s %s\n", Cyan("🔧 Features:"), White(strings.Join(model.Features, ", "))))
-	b.WriteString(fmt.Sprintf("%s %d tokens\n", Blue("📏 Context:"), model.ContextLen))
-	
+
+	b.WriteString(fmt.Sprintf("%s %s\n", CyanBold(Icon("📋")+"Model:"), YellowBold(model.Name)))
+	b.WriteString(fmt.Sprintf("%s %s\n", BlueBold(Icon("🏢")+"Provider:"), White(model.Provider)))
+	b.WriteString(fmt.Sprintf("%s %s\n", GreenBold(Icon("📝")+"Description:"), White(model.Description)))
+	b.WriteString(fmt.Sprintf("%s %s\n", Magenta(Icon("🎯")+"Best For:"), White(model.BestFor)))
+	b.WriteString(fmt.Sprintf("%s %s\n", Cyan(Icon("🔧")+"Features:"), White(strings.Join(model.Features, ", "))))
+	b.WriteString(fmt.Sprintf("%s %d tokens\n", Blue(Icon("📏")+"Context:"), model.ContextLen))
+
 	if model.IsThinking {
-		b.WriteString(fmt.Sprintf("%s %s\n", GreenBold("🧠 Special:"), White("Supports reasoning with <think> tokens")))
+		b.WriteString(fmt.Sprintf("%s %s\n", GreenBold(Icon("🧠")+"Special:"), White("Supports reasoning with <think> tokens")))
 	}
 	if model.IsMultimodal {
-		b.WriteString(fmt.Sprintf("%s %s\n", YellowBold("🖼️  Multimodal:"), White("Supports text and images")))
+		b.WriteString(fmt.Sprintf("%s %s\n", YellowBold(Icon("🖼️ ")+"Multimodal:"), White("Supports text and images")))
 	}
-	
+
 	return b.String()
 }
 
@@ -142,23 +147,23 @@ func FormatModelInfo(model Model) string {
 func FormatModelList(currentModel string) string {
 	var b strings.Builder
 	models := GetAvailableModels()
-	
-	b.WriteString(CyanBold("✨ Available Models:\n\n"))
-	
-	for _, id := range []string{"kimi", "deepseek-r1", "deepseek-v3", "llama-4", "mistral"} {
+
+	b.WriteString(CyanBold(Icon("✨") + "Available Models:\n\n"))
+
+	for _, id := range GetModelOrder() {
 		model := models[id]
 		current := ""
 		if id == currentModel {
 			current = GreenBold(" (current)")
 		}
-		
-		b.WriteString(fmt.Sprintf("%s %s%s\n", 
-			YellowBold(fmt.Sprintf("%-12s", id)), 
-			White(model.Name), 
+
+		b.WriteString(fmt.Sprintf("%s %s%s\n",
+			YellowBold(fmt.Sprintf("%-12s", id)),
+			White(model.Name),
 			current))
 		b.WriteString(fmt.Sprintf("             %s\n", Cyan(model.Description)))
 		b.WriteString("\n")
 	}
-	
+
 	return b.String()
-}
\ No newline at end of file
+}
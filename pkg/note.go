@@ -0,0 +1,434 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// Default patterns for note filenames, relative to the notes directory and without an extension
+const (
+	DefaultNoteFilename = "{{.Date}}-{{.Slug}}"
+	DefaultDailyNote    = "{{.Date}}"
+)
+
+// Note is a research result written to a notes directory, with the details of the query
+// kept in its front matter
+type Note struct {
+	// ID is the history entry the note came from, if it was recorded
+	ID       string
+	Time     time.Time
+	Query    string
+	Model    string
+	Template string
+	Result   FormattedResponse
+}
+
+// noteFilenameData holds the values a note filename pattern can use
+type noteFilenameData struct {
+	Date     string
+	Time     string
+	Year     string
+	Month    string
+	Day      string
+	Slug     string
+	Model    string
+	Template string
+	ID       string
+}
+
+// Filename expands a filename pattern such as "{{.Date}}-{{.Slug}}" for the note and adds
+// the extension. The result is a relative path that stays inside the notes directory.
+func (n Note) Filename(pattern string, ext string) (string, error) {
+	tmpl, err := template.New("filename").Parse(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid filename pattern: %w", err)
+	}
+
+	t := n.Time.Local()
+	var b strings.Builder
+	err = tmpl.Execute(&b, noteFilenameData{
+		Date:     t.Format("2006-01-02"),
+		Time:     t.Format("150405"),
+		Year:     t.Format("2006"),
+		Month:    t.Format("01"),
+		Day:      t.Format("02"),
+		Slug:     Slugify(n.Query),
+		Model:    n.Model,
+		Template: n.Template,
+		ID:       n.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid filename pattern: %w", err)
+	}
+
+	// Characters some file systems refuse become dashes; slashes still make subdirectories
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"\|?*`, r) || unicode.IsControl(r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(b.String()))
+	name = filepath.Clean(filepath.FromSlash(name))
+	if name == "." || !filepath.IsLocal(name) {
+		return "", errors.New("filename pattern must name a file inside the notes directory")
+	}
+	// A pattern that ends in an extension of its own gets the one for the format instead
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown", ".html", ".htm":
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name + ext, nil
+}
+
+// ValidateNoteFilename checks that a filename pattern expands to a usable relative path
+func ValidateNoteFilename(pattern string) error {
+	_, err := Note{Time: time.Now(), Query: "example", Model: GetDefaultModel(), Template: GetDefaultTemplate(), ID: "0"}.Filename(pattern, ".md")
+	return err
+}
+
+// Slugify turns text into a short lowercase file name part, keeping letters of any script
+func Slugify(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if b.Len() >= 60 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "note"
+	}
+	return b.String()
+}
+
+// Markdown returns the note as a Markdown document with YAML front matter
+func (n Note) Markdown() string {
+	var b strings.Builder
+
+	b.WriteString("---\n")
+	b.WriteString("query: " + yamlString(n.Query) + "\n")
+	b.WriteString("model: " + yamlString(n.Model) + "\n")
+	b.WriteString("template: " + yamlString(n.Template) + "\n")
+	b.WriteString("date: " + n.Time.Local().Format(time.RFC3339) + "\n")
+	if n.Result.Usage != nil {
+		b.WriteString(fmt.Sprintf("tokens: %d\n", n.Result.Usage.TotalTokens))
+	}
+	if len(n.Result.SourceLinks) == 0 {
+		b.WriteString("sources: []\n")
+	} else {
+		b.WriteString("sources:\n")
+		for _, link := range n.Result.SourceLinks {
+			b.WriteString("  - " + yamlString(link) + "\n")
+		}
+	}
+	if n.ID != "" {
+		b.WriteString("id: " + yamlString(n.ID) + "\n")
+	}
+	b.WriteString("---\n\n")
+
+	b.WriteString("# " + singleLine(n.Query) + "\n\n")
+	b.WriteString("## " + activeLocale.SummaryHeading + "\n\n" + strings.TrimSpace(n.Result.Summary) + "\n")
+	if len(n.Result.KeyPoints) > 0 {
+		b.WriteString("\n## " + activeLocale.KeyPointsHeading + "\n\n")
+		for i, point := range n.Result.KeyPoints {
+			marker := fmt.Sprintf("%d. ", i+1)
+			b.WriteString(marker + indentContinuation(point, strings.Repeat(" ", len(marker))) + "\n")
+		}
+	}
+	if len(n.Result.SourceLinks) > 0 {
+		b.WriteString("\n## " + activeLocale.SourcesHeading + "\n\n")
+		for _, link := range n.Result.SourceLinks {
+			b.WriteString("- " + link + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Entry returns the note as a section to append to a daily note, headed by the time and query
+func (n Note) Entry() string {
+	var b strings.Builder
+
+	b.WriteString("## " + n.Time.Local().Format("15:04") + " " + singleLine(n.Query) + "\n\n")
+	b.WriteString("_" + n.meta() + "_\n\n")
+	b.WriteString(strings.TrimSpace(n.Result.Summary) + "\n")
+	if len(n.Result.KeyPoints) > 0 {
+		b.WriteString("\n")
+		for _, point := range n.Result.KeyPoints {
+			b.WriteString("- " + indentContinuation(point, "  ") + "\n")
+		}
+	}
+	if len(n.Result.SourceLinks) > 0 {
+		b.WriteString("\n" + activeLocale.SourcesHeading + ":\n")
+		for _, link := range n.Result.SourceLinks {
+			b.WriteString("- " + link + "\n")
+		}
+	}
+	return b.String()
+}
+
+// HTML returns the note as a standalone HTML page
+func (n Note) HTML() string {
+	var b strings.Builder
+	title := html.EscapeString(singleLine(n.Query))
+
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	b.WriteString("<meta name=\"generator\" content=\"photon " + html.EscapeString(GetVersion()) + "\">\n")
+	b.WriteString("<title>" + title + "</title>\n<style>\n" + noteStyle + "</style>\n</head>\n<body>\n<article>\n")
+
+	b.WriteString("<h1>" + title + "</h1>\n")
+	b.WriteString("<p class=\"meta\">" + html.EscapeString(n.meta()) + "</p>\n")
+	b.WriteString("<h2>" + html.EscapeString(activeLocale.SummaryHeading) + "</h2>\n" + markdownToHTML(n.Result.Summary))
+	if len(n.Result.KeyPoints) > 0 {
+		b.WriteString("<h2>" + html.EscapeString(activeLocale.KeyPointsHeading) + "</h2>\n<ol>\n")
+		for _, point := range n.Result.KeyPoints {
+			b.WriteString("<li>\n" + markdownToHTML(point) + "</li>\n")
+		}
+		b.WriteString("</ol>\n")
+	}
+	if len(n.Result.SourceLinks) > 0 {
+		b.WriteString("<h2>" + html.EscapeString(activeLocale.SourcesHeading) + "</h2>\n<ul>\n")
+		for _, link := range n.Result.SourceLinks {
+			b.WriteString("<li>" + htmlLink(link, link) + "</li>\n")
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	b.WriteString("</article>\n</body>\n</html>\n")
+	return b.String()
+}
+
+// meta describes the model, time and token count of the note on one line
+func (n Note) meta() string {
+	name := n.Model
+	if model, err := GetModel(n.Model); err == nil {
+		name = model.Name
+	}
+	parts := []string{name, n.Time.Local().Format("2006-01-02 15:04")}
+	if n.Result.Usage != nil {
+		parts = append(parts, fmt.Sprintf("%d tokens", n.Result.Usage.TotalTokens))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// noteStyle is the stylesheet of exported HTML pages
+const noteStyle = `body { margin: 0; background: #fafafa; color: #222; font: 16px/1.6 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; }
+article { max-width: 46rem; margin: 2rem auto; padding: 0 1.25rem; }
+h1 { font-size: 1.7rem; line-height: 1.3; margin-bottom: 0.25rem; }
+h2 { font-size: 1.2rem; margin-top: 2rem; border-bottom: 1px solid #ddd; padding-bottom: 0.25rem; }
+.meta { color: #777; margin-top: 0; }
+a { color: #0b63c5; }
+code { background: #eee; border-radius: 3px; padding: 0.1em 0.3em; font-size: 0.9em; }
+pre { background: #f0f0f0; border-radius: 6px; padding: 0.75rem 1rem; overflow-x: auto; }
+pre code { background: none; padding: 0; }
+blockquote { margin: 0; padding-left: 1rem; border-left: 3px solid #ccc; color: #555; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3rem 0.6rem; text-align: left; }
+@media (prefers-color-scheme: dark) {
+  body { background: #1b1b1b; color: #ddd; }
+  h2 { border-color: #444; }
+  a { color: #6cb0ff; }
+  code, pre { background: #2a2a2a; }
+  th, td { border-color: #444; }
+}
+`
+
+// markdownToHTML converts the Markdown of a research answer to HTML, covering the same
+// elements RenderMarkdown draws in the terminal
+func markdownToHTML(md string) string {
+	var b strings.Builder
+	var paragraph []string
+	// lists holds the tags of the open lists, innermost last; each has an open <li>
+	var lists []string
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + inlineHTML(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeLists := func(depth int) {
+		for len(lists) > depth {
+			b.WriteString("</li>\n</" + lists[len(lists)-1] + ">\n")
+			lists = lists[:len(lists)-1]
+		}
+	}
+	listItem := func(tag string, leading string, text string) {
+		flush()
+		depth := len(listIndent(leading))/2 + 1
+		if depth > len(lists)+1 {
+			depth = len(lists) + 1
+		}
+		closeLists(depth)
+		if len(lists) == depth && lists[depth-1] != tag {
+			closeLists(depth - 1)
+		}
+		if len(lists) == depth {
+			b.WriteString("</li>\n")
+		} else {
+			b.WriteString("<" + tag + ">\n")
+			lists = append(lists, tag)
+		}
+		b.WriteString("<li>" + inlineHTML(text))
+	}
+
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+			closeLists(0)
+
+		case isCodeFence(trimmed):
+			flush()
+			closeLists(0)
+			fence := trimmed[:3]
+			lang := strings.TrimSpace(strings.TrimLeft(trimmed, "`~"))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			class := ""
+			if lang != "" {
+				class = ` class="language-` + html.EscapeString(lang) + `"`
+			}
+			b.WriteString("<pre><code" + class + ">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case isTableRow(trimmed) && i+1 < len(lines) && tableRulePattern.MatchString(strings.TrimSpace(lines[i+1])):
+			flush()
+			closeLists(0)
+			b.WriteString("<table>\n<thead>\n" + tableRowHTML(trimmed, "th") + "</thead>\n<tbody>\n")
+			for i += 2; i < len(lines) && isTableRow(strings.TrimSpace(lines[i])); i++ {
+				b.WriteString(tableRowHTML(strings.TrimSpace(lines[i]), "td"))
+			}
+			i--
+			b.WriteString("</tbody>\n</table>\n")
+
+		case headingPattern.MatchString(trimmed):
+			flush()
+			closeLists(0)
+			match := headingPattern.FindStringSubmatch(trimmed)
+			// The page already uses h1 and h2, so answer headings start at h3
+			tag := fmt.Sprintf("h%d", min(len(match[1])+2, 6))
+			b.WriteString("<" + tag + ">" + inlineHTML(match[2]) + "</" + tag + ">\n")
+
+		case rulePattern.MatchString(trimmed):
+			flush()
+			closeLists(0)
+			b.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			closeLists(0)
+			text := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			b.WriteString("<blockquote><p>" + inlineHTML(text) + "</p></blockquote>\n")
+
+		case orderedItemPattern.MatchString(line):
+			match := orderedItemPattern.FindStringSubmatch(line)
+			listItem("ol", match[1], match[3])
+
+		case bulletItemPattern.MatchString(line):
+			match := bulletItemPattern.FindStringSubmatch(line)
+			listItem("ul", match[1], match[2])
+
+		case len(lists) > 0:
+			// A wrapped line continues the open list item
+			b.WriteString(" " + inlineHTML(trimmed))
+
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+	closeLists(0)
+	return b.String()
+}
+
+// tableRowHTML renders a Markdown table row as an HTML row of the given cell tag
+func tableRowHTML(line string, cell string) string {
+	line = strings.TrimPrefix(strings.TrimSuffix(line, "|"), "|")
+	var b strings.Builder
+	b.WriteString("<tr>")
+	for _, text := range strings.Split(line, "|") {
+		b.WriteString("<" + cell + ">" + inlineHTML(strings.TrimSpace(text)) + "</" + cell + ">")
+	}
+	b.WriteString("</tr>\n")
+	return b.String()
+}
+
+// inlineHTML escapes text and converts inline code, links, bold and italic text to HTML
+func inlineHTML(text string) string {
+	text = html.EscapeString(text)
+
+	// Protect inline code and link targets from the other inline rules
+	var spans []string
+	text = inlineCodePattern.ReplaceAllStringFunc(text, func(s string) string {
+		spans = append(spans, "<code>"+inlineCodePattern.FindStringSubmatch(s)[1]+"</code>")
+		return fmt.Sprintf("\x00%d\x00", len(spans)-1)
+	})
+	text = inlineLinkPattern.ReplaceAllStringFunc(text, func(s string) string {
+		match := inlineLinkPattern.FindStringSubmatch(s)
+		spans = append(spans, htmlLink(html.UnescapeString(match[2]), html.UnescapeString(match[1])))
+		return fmt.Sprintf("\x00%d\x00", len(spans)-1)
+	})
+
+	text = inlineBoldPattern.ReplaceAllStringFunc(text, func(s string) string {
+		match := inlineBoldPattern.FindStringSubmatch(s)
+		return "<strong>" + match[1] + match[2] + "</strong>"
+	})
+	text = inlineItalicPattern.ReplaceAllStringFunc(text, func(s string) string {
+		match := inlineItalicPattern.FindStringSubmatch(s)
+		return match[1] + match[3] + "<em>" + match[2] + match[4] + "</em>"
+	})
+
+	for i, span := range spans {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), span, 1)
+	}
+	return text
+}
+
+// htmlLink renders a link, or just its text when the target is not a web or mail address
+func htmlLink(target string, text string) string {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "mailto") {
+		return html.EscapeString(text)
+	}
+	return `<a href="` + html.EscapeString(target) + `">` + html.EscapeString(text) + "</a>"
+}
+
+// yamlString quotes a value for YAML front matter; JSON strings are valid YAML scalars
+func yamlString(value string) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// singleLine collapses whitespace, including newlines, to single spaces
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// indentContinuation indents every line after the first so it stays inside a list item
+func indentContinuation(text string, indent string) string {
+	text = strings.TrimSpace(text)
+	return strings.ReplaceAll(text, "\n", "\n"+indent)
+}
@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Output formats for research results
+const (
+	OutputPretty   = "pretty"
+	OutputMarkdown = "markdown"
+	OutputJSON     = "json"
+	OutputPlain    = "plain"
+)
+
+// DefaultOutputFormat is the output format used when none is configured
+const DefaultOutputFormat = OutputPretty
+
+// GetOutputFormats returns the supported output formats
+func GetOutputFormats() []string {
+	return []string{OutputPretty, OutputMarkdown, OutputJSON, OutputPlain}
+}
+
+// ValidateOutputFormat checks if an output format is supported
+func ValidateOutputFormat(format string) bool {
+	for _, f := range GetOutputFormats() {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonResult is the JSON shape of a research result
+type jsonResult struct {
+	Query       string   `json:"query"`
+	Model       string   `json:"model"`
+	Summary     string   `json:"summary"`
+	KeyPoints   []string `json:"key_points"`
+	SourceLinks []string `json:"source_links,omitempty"`
+	Route       *Route   `json:"route,omitempty"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// FormatResult renders a research result in the given output format
+func FormatResult(format string, query string, modelID string, result FormattedResponse, width int) (string, error) {
+	switch format {
+	case OutputPretty:
+		return RenderResultView(result, width), nil
+
+	case OutputMarkdown:
+		return FormatResultMarkdown(query, modelID, result), nil
+
+	case OutputJSON:
+		keyPoints := result.KeyPoints
+		if keyPoints == nil {
+			keyPoints = []string{}
+		}
+		data, err := json.MarshalIndent(jsonResult{
+			Query:       query,
+			Model:       modelID,
+			Summary:     result.Summary,
+			KeyPoints:   keyPoints,
+			SourceLinks: result.SourceLinks,
+			Route:       result.Route,
+			Warnings:    result.Warnings,
+		}, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+
+	case OutputPlain:
+		var b strings.Builder
+		b.WriteString(result.Summary + "\n")
+		for i, point := range result.KeyPoints {
+			b.WriteString(fmt.Sprintf("%d. %s\n", i+1, point))
+		}
+		return b.String(), nil
+	}
+
+	return "", fmt.Errorf("unknown output format '%s'", format)
+}
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// DefaultPingTimeout bounds how long a single model probe may take
+const DefaultPingTimeout = 15 * time.Second
+
+// PingResult is the outcome of probing a model endpoint
+type PingResult struct {
+	ModelID   string
+	Available bool
+	Latency   time.Duration
+	Err       error
+}
+
+// apiError is the error body returned by OpenRouter
+type apiError struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// PingModel sends a minimal one-token request to a model and measures the round trip.
+// Only the connection settings of opts are used.
+func PingModel(ctx context.Context, modelID string, opts RequestOptions) PingResult {
+	client, err := opts.client()
+	if err != nil {
+		return PingResult{ModelID: modelID, Err: err}
+	}
+	return client.Ping(ctx, modelID)
+}
+
+// StatusError is an error response from the API, as opposed to a connection failure
+type StatusError struct {
+	Status  int
+	Message string
+	// RetryAfter is how long the API asked callers to wait before retrying, if it said
+	RetryAfter time.Duration
+}
+
+// Temporary reports whether retrying the request later may succeed
+func (e *StatusError) Temporary() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("%d: %s", e.Status, e.Message)
+}
+
+// statusError describes a failed API response, using the provider's message when there is one
+func statusError(resp *http.Response, body []byte) error {
+	var apiErr apiError
+	json.Unmarshal(body, &apiErr)
+	return &StatusError{Status: resp.StatusCode, Message: apiErr.Error.Message, RetryAfter: retryAfter(resp.Header)}
+}
+
+// retryAfter reads the wait requested by a Retry-After header, in seconds or as a date,
+// falling back to the X-RateLimit-Reset timestamp in milliseconds that OpenRouter sends
+func retryAfter(header http.Header) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(max(seconds, 0)) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(time.Until(date), 0)
+		}
+	}
+	if value := header.Get("X-RateLimit-Reset"); value != "" {
+		if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return max(time.Until(time.UnixMilli(millis)), 0)
+		}
+	}
+	return 0
+}
+
+// KeyInfo describes an API key as reported by the provider
+type KeyInfo struct {
+	Label string   `json:"label"`
+	Usage float64  `json:"usage"`
+	Limit *float64 `json:"limit"`
+}
+
+// CheckAPIKey asks the provider's key endpoint whether the API key in opts is valid.
+// Only the connection settings of opts are used.
+func CheckAPIKey(ctx context.Context, opts RequestOptions) (*KeyInfo, error) {
+	client, err := opts.client()
+	if err != nil {
+		return nil, err
+	}
+	return client.CheckKey(ctx)
+}
+
+// PingModels probes the given models concurrently and returns results in the same order
+func PingModels(ctx context.Context, modelIDs []string, opts RequestOptions) []PingResult {
+	client, err := opts.client()
+	if err != nil {
+		results := make([]PingResult, len(modelIDs))
+		for i, id := range modelIDs {
+			results[i] = PingResult{ModelID: id, Err: err}
+		}
+		return results
+	}
+	return client.PingAll(ctx, modelIDs)
+}
+
+// FormatPingResult returns a short colored status for a probe, such as "● 812ms"
+func FormatPingResult(result PingResult) string {
+	if !result.Available {
+		return RedBold("✗ unavailable")
+	}
+	return GreenBold("● ") + Green(formatLatency(result.Latency))
+}
+
+// FormatPingTable returns a status table for a set of probe results
+func FormatPingTable(results []PingResult) string {
+	models := GetAvailableModels()
+
+	var b strings.Builder
+	b.WriteString(CyanBold(fmt.Sprintf("%-12s %-24s %-15s %s", "MODEL", "NAME", "STATUS", "LATENCY")) + "\n")
+	for _, result := range results {
+		status := Green("available")
+		if !result.Available {
+			status = Red("unavailable")
+		}
+
+		line := fmt.Sprintf("%s %-24s %s %s",
+			YellowBold(fmt.Sprintf("%-12s", result.ModelID)),
+			models[result.ModelID].Name,
+			status+strings.Repeat(" ", max(15-len(ansi.Strip(status)), 1)),
+			formatLatency(result.Latency))
+		b.WriteString(line + "\n")
+
+		if result.Err != nil {
+			b.WriteString(fmt.Sprintf("             %s\n", Muted(result.Err.Error())))
+		}
+	}
+	return b.String()
+}
+
+// formatLatency renders a duration in milliseconds, or "-" if no request was made
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
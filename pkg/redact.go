@@ -0,0 +1,255 @@
+package pkg
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RedactRule is a pattern whose matches are replaced with placeholders before a query is sent
+type RedactRule struct {
+	// Name names the placeholders, so the rule "email" produces [EMAIL_1], [EMAIL_2] and so on
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	// Group, when set, redacts only that capture group and keeps the rest of the match,
+	// such as the "password=" in front of a password
+	Group int `json:"group,omitempty"`
+}
+
+// DefaultRedactRules returns the built-in rules for private keys, API keys, tokens,
+// passwords, email addresses, IP addresses and user names in home directory paths
+func DefaultRedactRules() []RedactRule {
+	return []RedactRule{
+		{Name: "private_key", Pattern: `(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`},
+		{Name: "api_key", Pattern: `\bsk-[A-Za-z0-9_-]{20,}|\b(?:AKIA|ASIA)[0-9A-Z]{16}\b|\bAIza[0-9A-Za-z_-]{35}\b`},
+		{Name: "token", Pattern: `\bgh[pousr]_[A-Za-z0-9]{36,}|\bgithub_pat_[A-Za-z0-9_]{22,}|\bxox[abprs]-[A-Za-z0-9-]{10,}|\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`},
+		{Name: "token", Pattern: `(?i)\b(?:bearer|basic|token)\s+([A-Za-z0-9._~+/-]{8,}=*)`, Group: 1},
+		{Name: "password", Pattern: `://[^/\s:@]+:([^/\s@]+)@`, Group: 1},
+		{Name: "password", Pattern: `(?i)\b(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|client[_-]?secret)["']?\s*[:=]\s*["']?([^\s"',;]{4,})`, Group: 1},
+		{Name: "email", Pattern: `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`},
+		{Name: "ip", Pattern: `\b(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\b|\b(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}\b`},
+		{Name: "user", Pattern: `(?:/home/|/Users/|\b[A-Za-z]:\\Users\\)([^/\\\s:'"]+)`, Group: 1},
+	}
+}
+
+// compiledRule is a RedactRule with its pattern compiled
+type compiledRule struct {
+	RedactRule
+	re *regexp.Regexp
+}
+
+// Redactor replaces sensitive text with numbered placeholders. A Redactor is safe for
+// concurrent use.
+type Redactor struct {
+	rules []compiledRule
+}
+
+// placeholderPattern matches the placeholders a Redactor inserts
+var placeholderPattern = regexp.MustCompile(`\[([A-Z0-9_]+)_([0-9]+)\]`)
+
+// ruleNamePattern restricts rule names to ones that make readable placeholders
+var ruleNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// NewRedactor creates a Redactor with the built-in rules followed by the custom ones
+func NewRedactor(custom ...RedactRule) (*Redactor, error) {
+	r := &Redactor{}
+	for _, rule := range append(DefaultRedactRules(), custom...) {
+		if !ruleNamePattern.MatchString(rule.Name) {
+			return nil, fmt.Errorf("redact rule name '%s' must start with a letter and contain only letters, digits and _", rule.Name)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact rule '%s': %w", rule.Name, err)
+		}
+		if rule.Group < 0 || rule.Group > re.NumSubexp() {
+			return nil, fmt.Errorf("redact rule '%s': pattern has no group %d", rule.Name, rule.Group)
+		}
+		r.rules = append(r.rules, compiledRule{RedactRule: rule, re: re})
+	}
+	return r, nil
+}
+
+// RedactedValue is one value a Redactor replaced
+type RedactedValue struct {
+	Rule        string
+	Original    string
+	Placeholder string
+}
+
+// Redaction is a redacted text together with what was replaced, so answers can be restored
+type Redaction struct {
+	Text   string
+	Values []RedactedValue
+}
+
+// Redact replaces every match of the rules with a placeholder. Repeated values share a
+// placeholder, so the model can still tell them apart.
+func (r *Redactor) Redact(text string) Redaction {
+	redaction := Redaction{Text: text}
+	if r == nil {
+		return redaction
+	}
+
+	placeholders := make(map[string]string)
+	// Number after any placeholders already in the text, so restoring never touches them
+	counts := make(map[string]int)
+	for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		if n, err := strconv.Atoi(match[2]); err == nil {
+			counts[match[1]] = max(counts[match[1]], n)
+		}
+	}
+	for _, rule := range r.rules {
+		var b strings.Builder
+		last := 0
+		for _, match := range rule.re.FindAllStringSubmatchIndex(redaction.Text, -1) {
+			start, end := match[2*rule.Group], match[2*rule.Group+1]
+			if start < 0 || start == end {
+				continue
+			}
+			// Skip values already redacted by an earlier rule and addresses that reveal nothing
+			value := redaction.Text[start:end]
+			if placeholderPattern.MatchString(value) || (rule.Name == "ip" && isLocalIP(value)) {
+				continue
+			}
+
+			placeholder, ok := placeholders[value]
+			if !ok {
+				name := strings.ToUpper(rule.Name)
+				counts[name]++
+				placeholder = fmt.Sprintf("[%s_%d]", name, counts[name])
+				placeholders[value] = placeholder
+				redaction.Values = append(redaction.Values, RedactedValue{Rule: rule.Name, Original: value, Placeholder: placeholder})
+			}
+			b.WriteString(redaction.Text[last:start] + placeholder)
+			last = end
+		}
+		b.WriteString(redaction.Text[last:])
+		redaction.Text = b.String()
+	}
+	return redaction
+}
+
+// placeholderNote tells the model how to treat placeholders, so they come back unchanged
+const placeholderNote = "Values in square brackets such as [EMAIL_1] are placeholders for redacted data; repeat them exactly as written when you refer to them."
+
+// query returns the redacted text as it is sent, with a note about placeholders if there are any
+func (r Redaction) query() string {
+	if len(r.Values) == 0 {
+		return r.Text
+	}
+	return r.Text + "\n\n" + placeholderNote
+}
+
+// isLocalIP reports whether an address is loopback or unspecified, which reveals nothing
+func isLocalIP(value string) bool {
+	ip := net.ParseIP(value)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// Restore puts the original values back wherever their placeholders appear in text
+func (r Redaction) Restore(text string) string {
+	if len(r.Values) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(r.Values))
+	for _, value := range r.Values {
+		pairs = append(pairs, value.Placeholder, value.Original)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// RestoreResponse restores the original values in every part of a parsed answer
+func (r Redaction) RestoreResponse(result FormattedResponse) FormattedResponse {
+	if len(r.Values) == 0 {
+		return result
+	}
+	result.Summary = r.Restore(result.Summary)
+	keyPoints := make([]string, len(result.KeyPoints))
+	for i, point := range result.KeyPoints {
+		keyPoints[i] = r.Restore(point)
+	}
+	result.KeyPoints = keyPoints
+	return result
+}
+
+// Redact puts the placeholders back wherever the original values appear in text, such as
+// an answer that was restored for display but is about to be stored
+func (r Redaction) Redact(text string) string {
+	if len(r.Values) == 0 {
+		return text
+	}
+	// Longer values first, so a value that contains another is replaced whole
+	values := slices.Clone(r.Values)
+	sort.SliceStable(values, func(i, j int) bool { return len(values[i].Original) > len(values[j].Original) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		pairs = append(pairs, value.Original, value.Placeholder)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// RedactResponse puts the placeholders back in every part of a restored answer
+func (r Redaction) RedactResponse(result FormattedResponse) FormattedResponse {
+	if len(r.Values) == 0 {
+		return result
+	}
+	result.Summary = r.Redact(result.Summary)
+	keyPoints := make([]string, len(result.KeyPoints))
+	for i, point := range result.KeyPoints {
+		keyPoints[i] = r.Redact(point)
+	}
+	result.KeyPoints = keyPoints
+	return result
+}
+
+// Rules returns the names of the rules that matched, sorted and without repeats
+func (r Redaction) Rules() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, value := range r.Values {
+		if !seen[value.Rule] {
+			seen[value.Rule] = true
+			names = append(names, value.Rule)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// restoreStream wraps onChunk so streamed pieces of the answer are restored before they are
+// passed on. A placeholder split across pieces is held back until it is complete; the
+// returned function passes on whatever is still held back.
+func (r Redaction) restoreStream(onChunk func(string)) (func(string), func()) {
+	if len(r.Values) == 0 || onChunk == nil {
+		return onChunk, func() {}
+	}
+
+	longest := 0
+	for _, value := range r.Values {
+		longest = max(longest, len(value.Placeholder))
+	}
+
+	var pending string
+	write := func(chunk string) {
+		pending += chunk
+		cut := len(pending)
+		if i := strings.LastIndexByte(pending, '['); i >= 0 && !strings.Contains(pending[i:], "]") && len(pending)-i < longest {
+			cut = i
+		}
+		if cut > 0 {
+			onChunk(r.Restore(pending[:cut]))
+			pending = pending[cut:]
+		}
+	}
+	flush := func() {
+		if pending != "" {
+			onChunk(r.Restore(pending))
+			pending = ""
+		}
+	}
+	return write, flush
+}
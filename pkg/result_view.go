@@ -0,0 +1,284 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// resultKeyMap defines the keybindings for the interactive result view
+type resultKeyMap struct {
+	CopySummary key.Binding
+	CopyPoint   key.Binding
+	Reask       key.Binding
+	FollowUp    key.Binding
+	Save        key.Binding
+	Quit        key.Binding
+	Confirm     key.Binding
+	Cancel      key.Binding
+}
+
+var resultKeys = resultKeyMap{
+	CopySummary: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "copy summary"),
+	),
+	CopyPoint: key.NewBinding(
+		key.WithKeys("1", "2", "3", "4", "5", "6", "7", "8", "9"),
+		key.WithHelp("1-9", "copy key point"),
+	),
+	Reask: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "ask another model"),
+	),
+	FollowUp: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "follow-up"),
+	),
+	Save: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "save"),
+	),
+	Quit: key.NewBinding(
+		key.WithKeys("q", "esc", "ctrl+c"),
+		key.WithHelp("q/esc", "quit"),
+	),
+	Confirm: key.NewBinding(
+		key.WithKeys("enter"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+	),
+}
+
+// Input modes of the result view prompt line
+const (
+	inputNone = iota
+	inputFollowUp
+)
+
+// ReaskMsg is emitted when the user wants to re-run the query with another model
+type ReaskMsg struct{}
+
+// SaveMsg is emitted when the user wants to save the result as a note; the note's path is
+// reported back with Saved
+type SaveMsg struct{}
+
+// FollowUpMsg is emitted when the user submits a follow-up question
+type FollowUpMsg struct {
+	Question string
+}
+
+// ResultViewModel is an interactive, scrollable view of a research result
+type ResultViewModel struct {
+	viewport  viewport.Model
+	input     textinput.Model
+	inputMode int
+	result    FormattedResponse
+	status    string
+	width     int
+	height    int
+}
+
+// NewResultView creates a result view for the given result
+func NewResultView(result FormattedResponse, width int, height int) ResultViewModel {
+	input := textinput.New()
+	input.CharLimit = 500
+
+	m := ResultViewModel{
+		viewport: viewport.New(width, height),
+		input:    input,
+		result:   result,
+	}
+	m.resize(width, height)
+	return m
+}
+
+// Init initializes the result view
+func (m ResultViewModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles scrolling, actions and prompt input
+func (m ResultViewModel) Update(msg tea.Msg) (ResultViewModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.resize(msg.Width, msg.Height)
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.inputMode != inputNone {
+			return m.updateInput(msg)
+		}
+
+		switch {
+		case key.Matches(msg, resultKeys.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, resultKeys.CopySummary):
+			m.copy(activeLocale.SummaryLabel, m.result.Summary)
+			return m, nil
+
+		case key.Matches(msg, resultKeys.CopyPoint):
+			index := int(msg.Runes[0] - '1')
+			if index >= len(m.result.KeyPoints) {
+				m.status = fmt.Sprintf(activeLocale.NoKeyPoint, index+1)
+				return m, nil
+			}
+			m.copy(fmt.Sprintf(activeLocale.KeyPoint, index+1), m.result.KeyPoints[index])
+			return m, nil
+
+		case key.Matches(msg, resultKeys.Reask):
+			return m, func() tea.Msg { return ReaskMsg{} }
+
+		case key.Matches(msg, resultKeys.FollowUp):
+			return m.startInput(inputFollowUp, activeLocale.FollowUp, "")
+
+		case key.Matches(msg, resultKeys.Save):
+			return m, func() tea.Msg { return SaveMsg{} }
+		}
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// startInput switches the prompt line into the given input mode
+func (m ResultViewModel) startInput(mode int, prompt string, value string) (ResultViewModel, tea.Cmd) {
+	m.inputMode = mode
+	m.status = ""
+	m.input.Prompt = prompt
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+	return m, m.input.Focus()
+}
+
+// updateInput handles key presses while the prompt line is active
+func (m ResultViewModel) updateInput(msg tea.KeyMsg) (ResultViewModel, tea.Cmd) {
+	switch {
+	case key.Matches(msg, resultKeys.Cancel):
+		m.inputMode = inputNone
+		m.input.Blur()
+		return m, nil
+
+	case key.Matches(msg, resultKeys.Confirm):
+		value := strings.TrimSpace(m.input.Value())
+		mode := m.inputMode
+		m.inputMode = inputNone
+		m.input.Blur()
+		if value == "" {
+			return m, nil
+		}
+
+		if mode == inputFollowUp {
+			return m, func() tea.Msg { return FollowUpMsg{Question: value} }
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// TimedOut shows that the last query got no answer in time, keeping the result on screen
+func (m ResultViewModel) TimedOut() ResultViewModel {
+	m.status = RedBold(activeLocale.Lost) + " " + Muted(activeLocale.LostHint)
+	return m
+}
+
+// Saved shows where the result was saved as a note, or why saving failed
+func (m ResultViewModel) Saved(path string, err error) ResultViewModel {
+	if err != nil {
+		m.status = RedBold("Error saving: ") + err.Error()
+	} else {
+		m.status = GreenBold(Icon("✅")+activeLocale.SavedTo) + path
+	}
+	return m
+}
+
+// copy sends text to the system clipboard using an OSC52 escape sequence
+func (m *ResultViewModel) copy(label string, text string) {
+	if text == "" {
+		m.status = fmt.Sprintf(activeLocale.NothingCopy, label)
+		return
+	}
+
+	seq := osc52.New(text)
+	if os.Getenv("TMUX") != "" {
+		seq = seq.Tmux()
+	} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+
+	if _, err := seq.WriteTo(os.Stderr); err != nil {
+		m.status = RedBold("Error copying: ") + err.Error()
+		return
+	}
+	m.status = GreenBold(Icon("✅") + fmt.Sprintf(activeLocale.Copied, label))
+}
+
+// resize fits the viewport into the window, leaving room for the footer
+func (m *ResultViewModel) resize(width int, height int) {
+	m.width = width
+	m.height = height
+	m.viewport.Width = width
+	m.viewport.Height = max(height-2, 1)
+	m.viewport.SetContent(RenderResultView(m.result, width))
+}
+
+// View renders the scrollable result with a status and help footer
+func (m ResultViewModel) View() string {
+	footerStyle := lipgloss.NewStyle().Foreground(ThemeColor(CurrentTheme().Muted))
+
+	var footer string
+	switch {
+	case m.inputMode != inputNone:
+		footer = m.input.View()
+	case m.status != "":
+		footer = m.status
+	default:
+		footer = footerStyle.Render(fmt.Sprintf("%3.f%% • %s", m.viewport.ScrollPercent()*100, activeLocale.ResultHelp))
+	}
+
+	return m.viewport.View() + "\n\n" + ansi.Truncate(footer, m.width, "…")
+}
+
+// Result returns the research result shown in the view
+func (m ResultViewModel) Result() FormattedResponse {
+	return m.result
+}
+
+// FormatResultMarkdown returns a research result as a Markdown document
+func FormatResultMarkdown(query string, modelID string, result FormattedResponse) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("# %s\n\n", query))
+	if model, err := GetModel(modelID); err == nil {
+		b.WriteString(fmt.Sprintf("_%s: %s_\n\n", activeLocale.Model, model.Name))
+	}
+	if route := FormatRoute(result.Route); route != "" {
+		b.WriteString("_" + route + "_\n\n")
+	}
+
+	b.WriteString("## " + activeLocale.SummaryHeading + "\n\n")
+	b.WriteString(result.Summary + "\n")
+
+	if len(result.KeyPoints) > 0 {
+		b.WriteString("\n## " + activeLocale.KeyPointsHeading + "\n\n")
+		for i, point := range result.KeyPoints {
+			b.WriteString(fmt.Sprintf("%d. %s\n", i+1, point))
+		}
+	}
+
+	return b.String()
+}
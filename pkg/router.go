@@ -0,0 +1,252 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// AutoModel is the pseudo-model that picks a model for each query
+const AutoModel = "auto"
+
+// Query categories the auto model tells apart
+const (
+	CategoryCoding    = "coding"
+	CategoryReasoning = "reasoning"
+	CategoryBilingual = "bilingual"
+	CategoryImage     = "image"
+	CategoryQuick     = "quick"
+	CategoryGeneral   = "general"
+)
+
+// GetRouteCategories returns the query categories in the order they are checked
+func GetRouteCategories() []string {
+	return []string{CategoryImage, CategoryBilingual, CategoryCoding, CategoryReasoning, CategoryQuick, CategoryGeneral}
+}
+
+// categoryModels picks the model for each category from what the models are good at
+var categoryModels = map[string]func(Model) bool{
+	CategoryCoding:    func(m Model) bool { return slices.Contains(m.Features, "Coding") },
+	CategoryReasoning: func(m Model) bool { return m.IsThinking },
+	CategoryBilingual: func(m Model) bool { return slices.Contains(m.Features, "Chinese & English") },
+	CategoryImage:     func(m Model) bool { return m.IsMultimodal && slices.Contains(m.Features, "Image Analysis") },
+	CategoryQuick:     func(m Model) bool { return slices.Contains(m.Features, "Fast") },
+	CategoryGeneral:   func(m Model) bool { return m.ID == GetDefaultModel() },
+}
+
+// RouteRule changes how the auto model routes queries. A rule with a pattern sends matching
+// queries to the rule's model, or to the category's model if it names none; a rule without
+// a pattern changes which model the category uses.
+type RouteRule struct {
+	Category string `json:"category"`
+	Pattern  string `json:"pattern,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// Route is the model the auto model picked for a query and why
+type Route struct {
+	Model    string `json:"model"`
+	Category string `json:"category"`
+	Reason   string `json:"reason"`
+}
+
+// compiledRouteRule is a RouteRule with its pattern compiled
+type compiledRouteRule struct {
+	RouteRule
+	re *regexp.Regexp
+}
+
+// Router picks a model for each query of the auto model. A Router is safe for concurrent use.
+type Router struct {
+	rules      []compiledRouteRule
+	models     map[string]string
+	classifier string
+}
+
+// NewRouter creates a Router with the given rules, earlier rules winning over later ones. With
+// a classifier model the category is asked of that model instead of guessed from the query.
+func NewRouter(classifier string, rules ...RouteRule) (*Router, error) {
+	if classifier != "" && !ValidateModel(classifier) {
+		return nil, fmt.Errorf("classifier model '%s' not found", classifier)
+	}
+
+	r := &Router{models: make(map[string]string), classifier: classifier}
+	models := GetAvailableModels()
+	for _, category := range GetRouteCategories() {
+		for _, id := range GetModelOrder() {
+			if categoryModels[category](models[id]) {
+				r.models[category] = id
+				break
+			}
+		}
+	}
+
+	overridden := make(map[string]bool)
+	for _, rule := range rules {
+		if !slices.Contains(GetRouteCategories(), rule.Category) {
+			return nil, fmt.Errorf("route rule category '%s' not found, expected one of: %s", rule.Category, strings.Join(GetRouteCategories(), ", "))
+		}
+		if rule.Model != "" && !ValidateModel(rule.Model) {
+			return nil, fmt.Errorf("route rule for %s: model '%s' not found", rule.Category, rule.Model)
+		}
+		if rule.Pattern == "" {
+			if rule.Model == "" {
+				return nil, fmt.Errorf("route rule for %s needs a pattern or a model", rule.Category)
+			}
+			if !overridden[rule.Category] {
+				overridden[rule.Category] = true
+				r.models[rule.Category] = rule.Model
+			}
+			continue
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("route rule for %s: %w", rule.Category, err)
+		}
+		r.rules = append(r.rules, compiledRouteRule{RouteRule: rule, re: re})
+	}
+	return r, nil
+}
+
+// Patterns the heuristics look for, in a query lowercased first
+var (
+	imagePattern     = regexp.MustCompile(`\.(png|jpe?g|gif|webp|bmp|svg)\b|\b(image|images|picture|photo|photos|screenshot|diagram|chart|logo)\b`)
+	codePattern      = regexp.MustCompile("```|\\b(func|def|class|struct|import|goroutine|stack ?trace|traceback|segfault|exception|null pointer|compiler?|syntax|regex|sdk|npm|pip|cargo|git|docker|kubernetes|kubectl|golang|python|javascript|typescript|rust|java|c\\+\\+|sql|bash|function|bug|debug|refactor|unit test)\\b|\\w\\(\\)|\\.(go|py|js|ts|rs|java|rb|cpp|sh|sql)\\b")
+	reasoningPattern = regexp.MustCompile(`\b(why|prove|proof|derive|analy[sz]e|analysis|compare|comparison|trade-?offs?|pros and cons|step by step|evaluate|implications?|versus|vs|should i|which is better|calculate|solve|equation|probability|optimi[sz]e)\b`)
+	quickPattern     = regexp.MustCompile(`^(what|who|when|where|which|define|how many|how much|how old|is|are|capital of)\b`)
+)
+
+// longQuery is the length in bytes beyond which a query is treated as a reasoning task
+const longQuery = 400
+
+// Route picks a model for a query from the rules and the heuristics. Queries asked for an
+// answer in a language other than English go to the bilingual model unless another
+// category fits better.
+func (r *Router) Route(query string, lang string) Route {
+	if route, ok := r.match(query); ok {
+		return route
+	}
+	category, reason := classifyQuery(query)
+	return r.route(category, reason, lang)
+}
+
+// match returns the route of the first rule whose pattern matches the query
+func (r *Router) match(query string) (Route, bool) {
+	for _, rule := range r.rules {
+		if rule.re.MatchString(query) {
+			reason := fmt.Sprintf("matches your %s rule", rule.Category)
+			model := rule.Model
+			if model == "" {
+				model, reason = r.model(rule.Category, reason)
+			}
+			return Route{Model: model, Category: rule.Category, Reason: reason}, true
+		}
+	}
+	return Route{}, false
+}
+
+// route returns the route for a category, preferring the bilingual model for general
+// questions asked for in another language
+func (r *Router) route(category string, reason string, lang string) Route {
+	if lang != "" && lang != DefaultLanguage && (category == CategoryGeneral || category == CategoryQuick) {
+		category, reason = CategoryBilingual, "answer asked for in "+localeName(lang)
+	}
+	model, reason := r.model(category, reason)
+	return Route{Model: model, Category: category, Reason: reason}
+}
+
+// model returns the model for a category, or the default model when no model qualifies for it
+func (r *Router) model(category string, reason string) (string, string) {
+	if model := r.models[category]; model != "" {
+		return model, reason
+	}
+	return GetDefaultModel(), fmt.Sprintf("%s; no model suits %s queries, so the default model answers", reason, category)
+}
+
+// Fallbacks returns the models to try in turn when the model of a route is unavailable: the
+// category's model, the other models that qualify for the category, then the default model
+func (r *Router) Fallbacks(route Route) []string {
+	candidates := []string{r.models[route.Category]}
+	if qualifies, ok := categoryModels[route.Category]; ok {
+		models := GetAvailableModels()
+		for _, id := range GetModelOrder() {
+			if qualifies(models[id]) {
+				candidates = append(candidates, id)
+			}
+		}
+	}
+	candidates = append(candidates, GetDefaultModel())
+
+	var fallbacks []string
+	for _, id := range candidates {
+		if id != "" && id != route.Model && !slices.Contains(fallbacks, id) {
+			fallbacks = append(fallbacks, id)
+		}
+	}
+	return fallbacks
+}
+
+// Classifier returns the model that classifies queries, or "" if the heuristics do
+func (r *Router) Classifier() string {
+	return r.classifier
+}
+
+// classifyQuery guesses a query's category and says why
+func classifyQuery(query string) (string, string) {
+	lower := strings.ToLower(query)
+	if match := imagePattern.FindString(lower); match != "" {
+		return CategoryImage, fmt.Sprintf("mentions '%s'", strings.TrimPrefix(match, "."))
+	}
+	if strings.IndexFunc(query, isCJK) >= 0 {
+		return CategoryBilingual, "contains Chinese, Japanese or Korean text"
+	}
+	if match := codePattern.FindString(lower); match != "" {
+		if match == "```" {
+			return CategoryCoding, "contains a code block"
+		}
+		return CategoryCoding, fmt.Sprintf("looks like a programming question ('%s')", match)
+	}
+	if match := reasoningPattern.FindString(lower); match != "" {
+		return CategoryReasoning, fmt.Sprintf("asks for analysis ('%s')", match)
+	}
+	if len(query) > longQuery {
+		return CategoryReasoning, "long, detailed question"
+	}
+	if len(strings.Fields(query)) <= 8 && quickPattern.MatchString(strings.TrimSpace(lower)) {
+		return CategoryQuick, "short factual question"
+	}
+	return CategoryGeneral, "general question"
+}
+
+// isCJK reports whether a rune is Chinese, Japanese or Korean script
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// localeName returns the name of a language, or the code if it is not supported
+func localeName(lang string) string {
+	if locale, err := GetLocale(lang); err == nil {
+		return locale.Name
+	}
+	return lang
+}
+
+// classifierPrompt asks a model for the category of a query
+var classifierPrompt = "Classify the user's query into exactly one of these categories: " +
+	strings.Join(GetRouteCategories(), ", ") + ". " +
+	"coding is programming and tooling, reasoning is analysis, comparisons, math or multi-step problems, " +
+	"bilingual is anything in or about Chinese, image is about pictures or visual content, " +
+	"quick is a short factual lookup, and general is everything else. Answer with the category name only."
+
+// parseCategory finds the category a classifier model answered with
+func parseCategory(answer string) (string, bool) {
+	answer = strings.ToLower(answer)
+	for _, category := range GetRouteCategories() {
+		if strings.Contains(answer, category) {
+			return category, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRouterFallbacks(t *testing.T) {
+	router, err := NewRouter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := router.Route("why is the sky blue", "")
+	if route.Category != CategoryReasoning {
+		t.Fatalf("got category %s, want reasoning", route.Category)
+	}
+
+	fallbacks := router.Fallbacks(route)
+	if slices.Contains(fallbacks, route.Model) {
+		t.Errorf("fallbacks %v include the routed model %s", fallbacks, route.Model)
+	}
+	if len(fallbacks) == 0 || fallbacks[len(fallbacks)-1] != GetDefaultModel() {
+		t.Errorf("fallbacks %v do not end with the default model", fallbacks)
+	}
+	models := GetAvailableModels()
+	for _, id := range fallbacks {
+		if id != GetDefaultModel() && !categoryModels[CategoryReasoning](models[id]) {
+			t.Errorf("fallback %s does not qualify for reasoning", id)
+		}
+	}
+}
+
+func TestRouterNoQualifyingModel(t *testing.T) {
+	router, err := NewRouter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(router.models, CategoryImage)
+
+	route := router.Route("describe this screenshot.png", "")
+	if route.Model != GetDefaultModel() {
+		t.Errorf("got model %s, want the default model", route.Model)
+	}
+	if !strings.Contains(route.Reason, "no model suits image queries") {
+		t.Errorf("got reason %q, want it to explain the fallback", route.Reason)
+	}
+}
+
+func TestClientFallback(t *testing.T) {
+	router, err := NewRouter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := "why is the sky blue"
+	routed, _ := GetModel(router.Route(query, "").Model)
+
+	var asked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		asked = append(asked, payload.Model)
+		if payload.Model == routed.APIName {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"message": "rate limited"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"content": "Summary:\nAnswered\n\nKey Points:\n1. Point"}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(WithAPIKey("sk-test"), WithBaseURL(server.URL), WithModel(AutoModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := client.Research(context.Background(), query)
+	if err != nil {
+		t.Fatalf("research failed instead of falling back: %v", err)
+	}
+	fallback := router.Fallbacks(Route{Model: routed.ID, Category: CategoryReasoning})[0]
+	if result.Route.Model != fallback {
+		t.Errorf("got model %s, want the fallback %s", result.Route.Model, fallback)
+	}
+	if !strings.Contains(result.Route.Reason, routed.ID+" was unavailable (429: rate limited)") {
+		t.Errorf("got reason %q, want it to report the fallback", result.Route.Reason)
+	}
+	if len(asked) != 2 {
+		t.Errorf("got %d requests, want 2", len(asked))
+	}
+
+	// Errors that another model would not fix are returned as is
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	if _, err := client.Research(context.Background(), query); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("got error %v, want the 401", err)
+	}
+}
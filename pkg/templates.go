@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Template is a prompt template for research queries. User prompts contain a
+// {{query}} placeholder that is replaced with the user's question.
+type Template struct {
+	Name        string
+	Description string
+	System      string
+	User        string
+	// ThinkingSystem and ThinkingUser replace System and User for thinking models when set
+	ThinkingSystem string
+	ThinkingUser   string
+	// Localized holds translated prompts by language code; templates without a translation
+	// are sent with an instruction to answer in the language instead
+	Localized map[string]Template
+}
+
+// responseFormat is the section layout every template asks for, so parseResponse can read it
+const responseFormat = "Summary:\n[Provide a concise 2-3 sentence summary without numbered points]\n\nKey Points:\n1. [First key point]\n2. [Second key point]\n3. [Third key point]"
+
+// responseFormatZh is responseFormat in Chinese
+const responseFormatZh = "摘要：\n[用 2-3 句话简要概括，不要编号]\n\n要点：\n1. [第一个要点]\n2. [第二个要点]\n3. [第三个要点]"
+
+// GetTemplates returns all built-in prompt templates
+func GetTemplates() map[string]Template {
+	return map[string]Template{
+		"research": {
+			Name:           "research",
+			Description:    "Balanced summary with three key points",
+			System:         "You are a research assistant that provides structured, factual information. Format your response with clear sections using exactly these headers: 'Summary:' and 'Key Points:'. Use emojis sparingly and only where they enhance understanding.",
+			User:           "{{query}}\n\nPlease structure your response as follows:\n\n" + responseFormat,
+			ThinkingSystem: "You are a research assistant that provides structured, factual information. Use your reasoning capabilities to analyze the query thoroughly. You can use <think> tags to show your reasoning process, then provide a clear final answer with 'Summary:' and 'Key Points:' sections.",
+			ThinkingUser:   "{{query}}\n\nPlease think through this query step by step, then provide your response in this format:\n\nSummary:\n[Provide a concise 2-3 sentence summary]\n\nKey Points:\n1. [First key point]\n2. [Second key point]\n3. [Third key point]",
+			Localized: map[string]Template{
+				"zh": {
+					System:         "你是一名研究助理，提供结构清晰、符合事实的信息。请用简体中文回答，并严格使用以下小节标题：'摘要：'和'要点：'。只在有助于理解时少量使用表情符号。",
+					User:           "{{query}}\n\n请按以下结构回答：\n\n" + responseFormatZh,
+					ThinkingSystem: "你是一名研究助理，提供结构清晰、符合事实的信息。请运用推理能力深入分析问题，可以用 <think> 标签展示推理过程，然后用简体中文给出包含'摘要：'和'要点：'两个小节的最终回答。",
+					ThinkingUser:   "{{query}}\n\n请逐步思考这个问题，然后按以下格式回答：\n\n" + responseFormatZh,
+				},
+			},
+		},
+		"brief": {
+			Name:        "brief",
+			Description: "One-sentence answer with short key points",
+			System:      "You are a research assistant that answers as briefly as possible while staying accurate. Format your response with exactly these headers: 'Summary:' and 'Key Points:'.",
+			User:        "{{query}}\n\nRespond in this format:\n\nSummary:\n[One sentence]\n\nKey Points:\n1. [Short point]\n2. [Short point]\n3. [Short point]",
+			Localized: map[string]Template{
+				"zh": {
+					System: "你是一名研究助理，在保证准确的前提下尽可能简短地回答。请用简体中文回答，并严格使用以下小节标题：'摘要：'和'要点：'。",
+					User:   "{{query}}\n\n请按以下格式回答：\n\n摘要：\n[一句话]\n\n要点：\n1. [简短要点]\n2. [简短要点]\n3. [简短要点]",
+				},
+			},
+		},
+		"explain": {
+			Name:        "explain",
+			Description: "Explain an error message with likely causes and fixes",
+			System:      "You are a senior engineer who diagnoses failures from shells, compilers, package managers, tests and services. Identify what failed and why, quoting the decisive line of the output, and give concrete fixes with exact commands where possible. Secrets, addresses and user names in the input may have been replaced with placeholders such as [API_KEY_1] or [USER_1]; treat those as opaque. Format your response with exactly these headers: 'Summary:' and 'Key Points:'.",
+			User:        "Explain this error:\n\n{{query}}\n\nRespond in this format:\n\nSummary:\n[What went wrong, in one or two sentences]\n\nKey Points:\n1. [Most likely cause and how to fix it]\n2. [Another possible cause and how to fix it]\n3. [How to confirm the fix or investigate further]",
+			Localized: map[string]Template{
+				"zh": {
+					System: "你是一名资深工程师，负责诊断 shell、编译器、包管理器、测试和服务中的故障。请指出失败的内容和原因，引用输出中起决定作用的那一行，并尽量给出包含确切命令的具体修复方法。输入中的密钥、地址和用户名可能已被替换为 [API_KEY_1] 或 [USER_1] 这样的占位符，请原样对待。请用简体中文回答，并严格使用以下小节标题：'摘要：'和'要点：'。",
+					User:   "解释这个错误：\n\n{{query}}\n\n请按以下格式回答：\n\n摘要：\n[用一两句话说明出了什么问题]\n\n要点：\n1. [最可能的原因及修复方法]\n2. [另一个可能的原因及修复方法]\n3. [如何确认修复或进一步排查]",
+				},
+			},
+		},
+		"deep-dive": {
+			Name:        "deep-dive",
+			Description: "Thorough explanation with examples and code where useful",
+			System:      "You are a senior research assistant. Give thorough, precise explanations and include examples, Markdown tables or fenced code blocks where they help. Format your response with exactly these headers: 'Summary:' and 'Key Points:'.",
+			User:        "{{query}}\n\nRespond in this format:\n\nSummary:\n[A detailed paragraph]\n\nKey Points:\n1. [Key point with explanation or example]\n2. [Key point with explanation or example]\n3. [Key point with explanation or example]\n4. [Key point with explanation or example]\n5. [Key point with explanation or example]",
+			Localized: map[string]Template{
+				"zh": {
+					System: "你是一名资深研究助理。请给出全面、准确的解释，并在有帮助时加入示例、Markdown 表格或代码块。请用简体中文回答，并严格使用以下小节标题：'摘要：'和'要点：'。",
+					User:   "{{query}}\n\n请按以下格式回答：\n\n摘要：\n[详细的一段话]\n\n要点：\n1. [要点及解释或示例]\n2. [要点及解释或示例]\n3. [要点及解释或示例]\n4. [要点及解释或示例]\n5. [要点及解释或示例]",
+				},
+			},
+		},
+	}
+}
+
+// GetDefaultTemplate returns the default template name
+func GetDefaultTemplate() string {
+	return "research"
+}
+
+// GetTemplate returns a template by name
+func GetTemplate(name string) (*Template, error) {
+	templates := GetTemplates()
+	if template, exists := templates[name]; exists {
+		return &template, nil
+	}
+	return nil, fmt.Errorf("template '%s' not found", name)
+}
+
+// ValidateTemplate checks if a template name is valid
+func ValidateTemplate(name string) bool {
+	_, exists := GetTemplates()[name]
+	return exists
+}
+
+// GetTemplateNames returns the names of all templates, sorted
+func GetTemplateNames() []string {
+	var names []string
+	for name := range GetTemplates() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Localize returns the template with its prompts in the given language. Templates without a
+// translation keep their prompts and ask the model to answer in the language.
+func (t Template) Localize(lang string) Template {
+	locale, err := GetLocale(lang)
+	if err != nil || locale.Code == DefaultLanguage {
+		return t
+	}
+	if localized, ok := t.Localized[locale.Code]; ok {
+		localized.Name, localized.Description = t.Name, t.Description
+		return localized
+	}
+	t.System += " " + locale.Instruction
+	if t.ThinkingSystem != "" {
+		t.ThinkingSystem += " " + locale.Instruction
+	}
+	return t
+}
+
+// BuildPrompts returns the system and user prompts for a query sent to the given model
+func (t Template) BuildPrompts(query string, model Model) (string, string) {
+	system, user := t.System, t.User
+	if model.IsThinking {
+		if t.ThinkingSystem != "" {
+			system = t.ThinkingSystem
+		}
+		if t.ThinkingUser != "" {
+			user = t.ThinkingUser
+		}
+	}
+	return system, strings.ReplaceAll(user, "{{query}}", query)
+}
@@ -0,0 +1,274 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Theme defines the palette used for terminal output. Colors accept ANSI numbers
+// ("6"), 256-color numbers ("86") or hex values ("#5fd7ff"); an empty color leaves
+// the terminal default in place.
+type Theme struct {
+	// Base names a built-in theme that custom themes inherit unset colors from
+	Base string `json:"base,omitempty"`
+
+	Primary       string `json:"primary,omitempty"`
+	Accent        string `json:"accent,omitempty"`
+	Success       string `json:"success,omitempty"`
+	Info          string `json:"info,omitempty"`
+	Error         string `json:"error,omitempty"`
+	Special       string `json:"special,omitempty"`
+	Text          string `json:"text,omitempty"`
+	Muted         string `json:"muted,omitempty"`
+	Faint         string `json:"faint,omitempty"`
+	Selection     string `json:"selection,omitempty"`
+	SelectionText string `json:"selection_text,omitempty"`
+	Code          string `json:"code,omitempty"`
+	Keyword       string `json:"keyword,omitempty"`
+	String        string `json:"string,omitempty"`
+	Number        string `json:"number,omitempty"`
+	Comment       string `json:"comment,omitempty"`
+}
+
+// DefaultThemeName is the theme used when none is configured
+const DefaultThemeName = "dark"
+
+// builtinThemes are the themes shipped with photon
+var builtinThemes = map[string]Theme{
+	"dark": {
+		Primary:       "6",
+		Accent:        "3",
+		Success:       "2",
+		Info:          "4",
+		Error:         "1",
+		Special:       "5",
+		Text:          "7",
+		Muted:         "244",
+		Faint:         "240",
+		Selection:     "63",
+		SelectionText: "15",
+		Code:          "13",
+		Keyword:       "5",
+		String:        "2",
+		Number:        "3",
+		Comment:       "8",
+	},
+	"light": {
+		Primary:       "30",
+		Accent:        "130",
+		Success:       "28",
+		Info:          "25",
+		Error:         "160",
+		Special:       "90",
+		Text:          "235",
+		Muted:         "243",
+		Faint:         "248",
+		Selection:     "153",
+		SelectionText: "16",
+		Code:          "125",
+		Keyword:       "90",
+		String:        "28",
+		Number:        "130",
+		Comment:       "245",
+	},
+	"high-contrast": {
+		Primary:       "14",
+		Accent:        "11",
+		Success:       "10",
+		Info:          "12",
+		Error:         "9",
+		Special:       "13",
+		Text:          "15",
+		Muted:         "252",
+		Faint:         "250",
+		Selection:     "11",
+		SelectionText: "0",
+		Code:          "13",
+		Keyword:       "13",
+		String:        "10",
+		Number:        "11",
+		Comment:       "250",
+	},
+	"monochrome": {},
+}
+
+var (
+	activeTheme  = builtinThemes[DefaultThemeName]
+	colorEnabled = true
+	emojiEnabled = true
+)
+
+// ThemeNames returns the names of all built-in themes plus the given custom ones, sorted
+func ThemeNames(custom map[string]Theme) []string {
+	var names []string
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	for name := range custom {
+		if _, exists := builtinThemes[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveTheme looks up a theme by name, preferring custom themes over built-in ones.
+// Custom themes inherit any unset color from their base theme (dark by default).
+func ResolveTheme(name string, custom map[string]Theme) (Theme, error) {
+	if name == "" {
+		name = DefaultThemeName
+	}
+
+	if theme, exists := custom[name]; exists {
+		base := theme.Base
+		if base == "" {
+			base = DefaultThemeName
+		}
+		parent, exists := builtinThemes[base]
+		if !exists {
+			return Theme{}, fmt.Errorf("theme '%s' has unknown base theme '%s'", name, base)
+		}
+		return mergeTheme(parent, theme), nil
+	}
+
+	if theme, exists := builtinThemes[name]; exists {
+		return theme, nil
+	}
+	return Theme{}, fmt.Errorf("theme '%s' not found", name)
+}
+
+// mergeTheme fills the unset colors of override from base
+func mergeTheme(base Theme, override Theme) Theme {
+	pick := func(b, o string) string {
+		if o != "" {
+			return o
+		}
+		return b
+	}
+
+	return Theme{
+		Base:          override.Base,
+		Primary:       pick(base.Primary, override.Primary),
+		Accent:        pick(base.Accent, override.Accent),
+		Success:       pick(base.Success, override.Success),
+		Info:          pick(base.Info, override.Info),
+		Error:         pick(base.Error, override.Error),
+		Special:       pick(base.Special, override.Special),
+		Text:          pick(base.Text, override.Text),
+		Muted:         pick(base.Muted, override.Muted),
+		Faint:         pick(base.Faint, override.Faint),
+		Selection:     pick(base.Selection, override.Selection),
+		SelectionText: pick(base.SelectionText, override.SelectionText),
+		Code:          pick(base.Code, override.Code),
+		Keyword:       pick(base.Keyword, override.Keyword),
+		String:        pick(base.String, override.String),
+		Number:        pick(base.Number, override.Number),
+		Comment:       pick(base.Comment, override.Comment),
+	}
+}
+
+// SetTheme makes theme the active palette for all output
+func SetTheme(theme Theme) {
+	activeTheme = theme
+}
+
+// CurrentTheme returns the active palette
+func CurrentTheme() Theme {
+	return activeTheme
+}
+
+// DisableColor turns off all colors and text attributes, as requested by NO_COLOR or --no-color
+func DisableColor() {
+	colorEnabled = false
+	lipgloss.SetColorProfile(termenv.Ascii)
+}
+
+// ColorEnabled reports whether output is styled
+func ColorEnabled() bool {
+	return colorEnabled && lipgloss.ColorProfile() != termenv.Ascii
+}
+
+// ColorProfileName names the color support in use, such as "TrueColor" or "Ascii"
+func ColorProfileName() string {
+	return lipgloss.ColorProfile().Name()
+}
+
+// EmojiEnabled reports whether emoji icons are shown
+func EmojiEnabled() bool {
+	return emojiEnabled
+}
+
+// SetEmoji enables or disables emoji icons in output
+func SetEmoji(enabled bool) {
+	emojiEnabled = enabled
+}
+
+// Icon returns the emoji followed by a space, or an empty string when emoji are disabled
+func Icon(emoji string) string {
+	if !emojiEnabled {
+		return ""
+	}
+	return emoji + " "
+}
+
+// textStyle describes how a piece of text is painted using a color of the active theme
+type textStyle struct {
+	color     func(Theme) string
+	bold      bool
+	italic    bool
+	underline bool
+}
+
+// render paints the arguments with the style, honoring the terminal color profile
+func (s textStyle) render(a ...interface{}) string {
+	text := fmt.Sprint(a...)
+	if !ColorEnabled() {
+		return text
+	}
+
+	profile := lipgloss.ColorProfile()
+	out := profile.String(text)
+	if s.color != nil {
+		if c := s.color(activeTheme); c != "" {
+			out = out.Foreground(profile.Color(c))
+		}
+	}
+	if s.bold {
+		out = out.Bold()
+	}
+	if s.italic {
+		out = out.Italic()
+	}
+	if s.underline {
+		out = out.Underline()
+	}
+	return out.String()
+}
+
+// Accessors for the theme colors used by text styles
+func primaryColor(t Theme) string { return t.Primary }
+func accentColor(t Theme) string  { return t.Accent }
+func successColor(t Theme) string { return t.Success }
+func infoColor(t Theme) string    { return t.Info }
+func errorColor(t Theme) string   { return t.Error }
+func specialColor(t Theme) string { return t.Special }
+func textColor(t Theme) string    { return t.Text }
+func mutedColor(t Theme) string   { return t.Muted }
+func faintColor(t Theme) string   { return t.Faint }
+func codeColor(t Theme) string    { return t.Code }
+func keywordColor(t Theme) string { return t.Keyword }
+func stringColor(t Theme) string  { return t.String }
+func numberColor(t Theme) string  { return t.Number }
+func commentColor(t Theme) string { return t.Comment }
+
+// ThemeColor returns a lipgloss color for a theme color value
+func ThemeColor(c string) lipgloss.TerminalColor {
+	if c == "" || !ColorEnabled() {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
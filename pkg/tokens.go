@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Answer budgets. Models are asked for at most maxAnswerTokens (maxThinkingAnswerTokens for
+// thinking models, whose reasoning counts too), and prompts are trimmed so that at least
+// minAnswerTokens remain for the answer.
+const (
+	maxAnswerTokens         = 8192
+	maxThinkingAnswerTokens = 16384
+	minAnswerTokens         = 1024
+)
+
+// messageOverhead is the tokens a chat message takes beyond its content, for the role and separators
+const messageOverhead = 4
+
+// truncationMarker starts a query whose oldest part was left out to fit the context window
+const truncationMarker = "[Earlier content was left out to fit the model's context window.]\n"
+
+// EstimateTokens approximates how many tokens text takes. It counts a token for every four
+// characters of a word, every punctuation mark and every Chinese, Japanese or Korean
+// character, which is close to common tokenizers and errs on the high side.
+func EstimateTokens(text string) int {
+	tokens, word := 0, 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case isCJK(r), unicode.IsPunct(r), unicode.IsSymbol(r):
+			flush()
+			tokens++
+		default:
+			word++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// EstimatePromptTokens approximates the tokens a system and user prompt take as chat messages
+func EstimatePromptTokens(system string, user string) int {
+	return EstimateTokens(system) + EstimateTokens(user) + 2*messageOverhead
+}
+
+// ContextBudget is how a model's context window is split between the prompt and the answer
+type ContextBudget struct {
+	// ContextLen is the model's context window
+	ContextLen int
+	// Prompt is the estimated size of the prompt
+	Prompt int
+	// MaxTokens is the most the answer may take, sent as max_tokens
+	MaxTokens int
+}
+
+// Fits reports whether the prompt leaves enough room for an answer
+func (b ContextBudget) Fits() bool {
+	return b.Prompt+minAnswerTokens <= b.usable()
+}
+
+// usable is the part of the context window the estimate may fill. Estimates are approximate,
+// so a twentieth of the window is kept in reserve.
+func (b ContextBudget) usable() int {
+	return b.ContextLen - b.ContextLen/20
+}
+
+// NewContextBudget splits a model's context window between a prompt and its answer
+func NewContextBudget(model Model, system string, user string) ContextBudget {
+	budget := ContextBudget{ContextLen: model.ContextLen, Prompt: EstimatePromptTokens(system, user)}
+	limit := maxAnswerTokens
+	if model.IsThinking {
+		limit = maxThinkingAnswerTokens
+	}
+	budget.MaxTokens = max(min(budget.usable()-budget.Prompt, limit), 0)
+	return budget
+}
+
+// TruncateToTokens keeps the end of text, the most recent part of a conversation or log, so
+// that it takes at most limit tokens, starting at a line break where one is near. It returns
+// the text unchanged if it already fits.
+func TruncateToTokens(text string, limit int) string {
+	if EstimateTokens(text) <= limit {
+		return text
+	}
+	limit -= EstimateTokens(truncationMarker)
+	if limit <= 0 {
+		return strings.TrimSuffix(truncationMarker, "\n")
+	}
+
+	// Find the earliest rune to start at that fits; tokens only shrink as the start moves later
+	var starts []int
+	for i := range text {
+		starts = append(starts, i)
+	}
+	i := sort.Search(len(starts), func(i int) bool { return EstimateTokens(text[starts[i]:]) <= limit })
+	start := len(text)
+	if i < len(starts) {
+		start = starts[i]
+	}
+
+	// Prefer starting on a new line if that loses little
+	if i := strings.IndexByte(text[start:], '\n'); i >= 0 && i < 200 {
+		start += i + 1
+	}
+	return truncationMarker + text[start:]
+}
+
+// fitQuery trims the oldest part of a query that would not fit in the model's context window
+// with room left for an answer. It returns the query to send and a warning when it trimmed.
+func fitQuery(template Template, model Model, query string) (string, string) {
+	system, user := template.BuildPrompts(query, model)
+	budget := NewContextBudget(model, system, user)
+	if budget.Fits() {
+		return query, ""
+	}
+
+	// Everything but the query itself, such as the system prompt and the format instructions
+	system, frame := template.BuildPrompts("", model)
+	available := budget.usable() - minAnswerTokens - EstimatePromptTokens(system, frame)
+	trimmed := TruncateToTokens(query, available)
+
+	kept := EstimateTokens(trimmed)
+	warning := fmt.Sprintf("the query is about %d tokens, more than %s's %d-token context window leaves room for; "+
+		"only its last %d tokens are sent", EstimateTokens(query), model.Name, model.ContextLen, kept)
+	return trimmed, warning
+}
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"whitespace", " \n\t ", 0},
+		{"short word", "go", 1},
+		{"four letters", "rust", 1},
+		{"five letters", "golang", 2},
+		{"words", "what is go", 3},
+		{"punctuation", "hello, world!", 6},
+		{"symbols", "a+b=c", 5},
+		{"chinese", "你好世界", 4},
+		{"chinese punctuation", "你好，世界。", 6},
+		{"japanese", "こんにちは", 5},
+		{"mixed", "Go 语言", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.want {
+				t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimatePromptTokens(t *testing.T) {
+	if got, want := EstimatePromptTokens("", ""), 2*messageOverhead; got != want {
+		t.Errorf("empty prompts: got %d, want %d", got, want)
+	}
+	if got, want := EstimatePromptTokens("be brief", "what is go"), 3+3+2*messageOverhead; got != want {
+		t.Errorf("got %d, want %d", got, want)
+	}
+}
+
+func TestNewContextBudget(t *testing.T) {
+	prompt := strings.Repeat("word ", 1000)
+	promptTokens := EstimatePromptTokens("", prompt)
+
+	tests := []struct {
+		name  string
+		model Model
+		user  string
+		want  int
+		fits  bool
+	}{
+		{"answer cap", Model{ContextLen: 128000}, "hi", maxAnswerTokens, true},
+		{"thinking answer cap", Model{ContextLen: 128000, IsThinking: true}, "hi", maxThinkingAnswerTokens, true},
+		{"small window", Model{ContextLen: 4000}, prompt, 4000 - 4000/20 - promptTokens, true},
+		{"thinking small window", Model{ContextLen: 4000, IsThinking: true}, prompt, 4000 - 4000/20 - promptTokens, true},
+		{"too little room", Model{ContextLen: 2000}, prompt, 2000 - 2000/20 - promptTokens, false},
+		{"prompt too large", Model{ContextLen: 1000}, prompt, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := NewContextBudget(tt.model, "", tt.user)
+			if budget.MaxTokens != tt.want {
+				t.Errorf("MaxTokens = %d, want %d", budget.MaxTokens, tt.want)
+			}
+			if budget.Fits() != tt.fits {
+				t.Errorf("Fits() = %v, want %v", budget.Fits(), tt.fits)
+			}
+			if budget.ContextLen != tt.model.ContextLen {
+				t.Errorf("ContextLen = %d, want %d", budget.ContextLen, tt.model.ContextLen)
+			}
+		})
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	marker := EstimateTokens(truncationMarker)
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, "line with some words in it")
+	}
+	log := strings.Join(lines, "\n")
+	chinese := strings.Repeat("错误信息", 100)
+
+	t.Run("fits", func(t *testing.T) {
+		if got := TruncateToTokens("what is go", 10); got != "what is go" {
+			t.Errorf("got %q, want the text unchanged", got)
+		}
+	})
+
+	t.Run("line boundary", func(t *testing.T) {
+		limit := marker + 50
+		got := TruncateToTokens(log, limit)
+		kept, ok := strings.CutPrefix(got, truncationMarker)
+		if !ok {
+			t.Fatalf("got %q, want it to start with the truncation marker", got)
+		}
+		if !strings.HasSuffix(log, kept) {
+			t.Error("kept text is not the end of the input")
+		}
+		if !strings.HasPrefix(kept, "line with") {
+			t.Errorf("kept text starts mid-line: %q", kept[:20])
+		}
+		if tokens := EstimateTokens(got); tokens > limit {
+			t.Errorf("got %d tokens, want at most %d", tokens, limit)
+		}
+	})
+
+	t.Run("rune boundary", func(t *testing.T) {
+		limit := marker + 37
+		got := TruncateToTokens(chinese, limit)
+		if !utf8.ValidString(got) {
+			t.Fatal("truncated text is not valid UTF-8")
+		}
+		kept := strings.TrimPrefix(got, truncationMarker)
+		if !strings.HasSuffix(chinese, kept) {
+			t.Error("kept text is not the end of the input")
+		}
+		if tokens := EstimateTokens(kept); tokens != 37 {
+			t.Errorf("kept %d tokens, want 37", tokens)
+		}
+	})
+
+	t.Run("less room than the marker", func(t *testing.T) {
+		for _, limit := range []int{-5, 0, 1, marker} {
+			got := TruncateToTokens(log, limit)
+			if got != strings.TrimSuffix(truncationMarker, "\n") {
+				t.Errorf("limit %d: got %q, want only the marker", limit, got)
+			}
+		}
+	})
+}
+
+func TestFitQuery(t *testing.T) {
+	template := Template{Name: "test", System: "Answer briefly.", User: "{{query}}\n\nSummary:"}
+	model := Model{Name: "Tiny", ContextLen: 4000}
+
+	if query, warning := fitQuery(template, model, "what is go"); query != "what is go" || warning != "" {
+		t.Errorf("small query: got %q, %q, want it unchanged without a warning", query, warning)
+	}
+
+	long := strings.Repeat("older log line with several words\n", 1000)
+	query, warning := fitQuery(template, model, long+"latest error")
+	if !strings.HasPrefix(query, truncationMarker) || !strings.HasSuffix(query, "latest error") {
+		t.Errorf("got %.80q…, want the marker followed by the end of the query", query)
+	}
+	if !strings.Contains(warning, "Tiny's 4000-token context window") {
+		t.Errorf("got warning %q, want it to name the model and its window", warning)
+	}
+
+	system, user := template.BuildPrompts(query, model)
+	if budget := NewContextBudget(model, system, user); !budget.Fits() || budget.MaxTokens < minAnswerTokens {
+		t.Errorf("trimmed query leaves %+v, want room for an answer", budget)
+	}
+}
@@ -2,30 +2,32 @@ package pkg
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/fatih/color"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
 )
 
-// Color functions for terminal output
+// Color functions for terminal output. The names describe the dark theme;
+// the actual colors follow the role each one plays in the active Theme.
 var (
-	CyanBold   = color.New(color.FgCyan, color.Bold).SprintFunc()
-	GreenBold  = color.New(color.FgGreen, color.Bold).SprintFunc()
-	YellowBold = color.New(color.FgYellow, color.Bold).SprintFunc()
-	BlueBold   = color.New(color.FgBlue, color.Bold).SprintFunc()
-	RedBold    = color.New(color.FgRed, color.Bold).SprintFunc()
-	White      = color.New(color.FgWhite).SprintFunc()
-	Cyan       = color.New(color.FgCyan).SprintFunc()
-	Blue       = color.New(color.FgBlue).SprintFunc()
-	Green      = color.New(color.FgGreen).SprintFunc()
-	Magenta    = color.New(color.FgMagenta).SprintFunc()
+	CyanBold   = textStyle{color: primaryColor, bold: true}.render
+	GreenBold  = textStyle{color: successColor, bold: true}.render
+	YellowBold = textStyle{color: accentColor, bold: true}.render
+	BlueBold   = textStyle{color: infoColor, bold: true}.render
+	RedBold    = textStyle{color: errorColor, bold: true}.render
+	White      = textStyle{color: textColor}.render
+	Cyan       = textStyle{color: primaryColor}.render
+	Blue       = textStyle{color: infoColor}.render
+	Green      = textStyle{color: successColor}.render
+	Red        = textStyle{color: errorColor}.render
+	Magenta    = textStyle{color: specialColor}.render
+	Muted      = textStyle{color: mutedColor}.render
 )
 
-// UI styling constants
-var SpinnerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("69"))
-
 // UIModel represents the UI state for rendering
 type UIModel struct {
 	Spinner  spinner.Model
@@ -37,37 +39,93 @@ type UIModel struct {
 func CreateSpinner() spinner.Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
-	s.Style = SpinnerStyle
+	s.Style = lipgloss.NewStyle().Foreground(ThemeColor(activeTheme.Primary))
 	return s
 }
 
 // RenderLoadingView renders the loading state with spinner
 func RenderLoadingView(uiModel UIModel) string {
 	if uiModel.Fallback {
-		return RedBold("\nLost in the tunnel of knowledge. Please try again later.\n")
+		return RedBold("\n"+activeLocale.Lost+"\n") + Muted(activeLocale.LostHint+"\n")
 	}
-	return fmt.Sprintf("\n %s %s\n\n", uiModel.Spinner.View(), CyanBold("THINKING.."))
+	return fmt.Sprintf("\n %s %s\n\n", uiModel.Spinner.View(), CyanBold(activeLocale.Thinking))
 }
 
-// RenderResultView renders the formatted research results
-func RenderResultView(result FormattedResponse) string {
+// maxResultWidth caps the result layout so lines stay readable on very wide terminals
+const maxResultWidth = 100
+
+// RenderResultView renders the formatted research results wrapped to the given width
+func RenderResultView(result FormattedResponse, width int) string {
+	width = min(max(width, 20), maxResultWidth)
+
 	b := strings.Builder{}
-	b.WriteString("\n" + CyanBold("✨ === PHOTON RESEARCH RESULTS === ✨") + "\n")
-	b.WriteString("\n" + YellowBold("✨ SUMMARY:") + "\n")
-	b.WriteString(White(result.Summary) + "\n")
+	b.WriteString("\n" + CyanBold(renderBanner(activeLocale.ResultsTitle, width)) + "\n")
+	b.WriteString("\n" + YellowBold(Icon("✨")+activeLocale.Summary) + "\n")
+	b.WriteString(RenderMarkdown(result.Summary, width) + "\n")
 
 	if len(result.KeyPoints) > 0 {
-		b.WriteString("\n" + GreenBold("💡 KEY POINTS:") + "\n")
+		b.WriteString("\n" + GreenBold(Icon("💡")+activeLocale.KeyPoints) + "\n")
 		for i, point := range result.KeyPoints {
-			b.WriteString(fmt.Sprintf("%s %d. %s\n", Cyan("➤"), i+1, White(point)))
+			prefix := fmt.Sprintf("%s %d. ", Cyan("➤"), i+1)
+			b.WriteString(hangingIndent(prefix, RenderMarkdown(point, width-ansi.StringWidth(prefix))) + "\n")
 		}
 	}
 
-	b.WriteString("\n" + CyanBold("✨ ========================== ✨") + "\n")
+	b.WriteString("\n" + CyanBold(renderBanner("", width)) + "\n")
+	if route := FormatRoute(result.Route); route != "" {
+		b.WriteString(Muted(ansi.Wrap(route, width, "")) + "\n")
+	}
 	return b.String()
 }
 
+// FormatRoute describes the model the auto model picked and why, or returns "" for a fixed model
+func FormatRoute(route *Route) string {
+	if route == nil {
+		return ""
+	}
+	name := route.Model
+	if model, err := GetModel(route.Model); err == nil {
+		name = model.Name
+	}
+	return fmt.Sprintf(activeLocale.AutoRoute, name, route.Reason)
+}
+
+// renderBanner draws a "✨ === title === ✨" rule that fills the given width
+func renderBanner(title string, width int) string {
+	if title != "" {
+		title = " " + title + " "
+	}
+
+	left, right := Icon("✨"), ""
+	if left != "" {
+		right = " ✨"
+	}
+
+	fill := width - ansi.StringWidth(left+title+right)
+	if fill < 2 {
+		return ansi.Truncate(strings.TrimSpace(title), width, "…")
+	}
+
+	half := fill / 2
+	return left + strings.Repeat("=", half) + title + strings.Repeat("=", fill-half) + right
+}
+
+// hangingIndent prefixes the first line of text and aligns the remaining lines under it
+func hangingIndent(prefix string, text string) string {
+	indent := strings.Repeat(" ", ansi.StringWidth(prefix))
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+indent)
+}
+
+// TerminalWidth returns the width of the terminal on stdout, or 80 if it is unknown
+func TerminalWidth() int {
+	width, _, err := term.GetSize(os.Stdout.Fd())
+	if err != nil || width <= 0 {
+		return 80
+	}
+	return width
+}
+
 // PrintFormattedResearch prints research results directly to console
 func PrintFormattedResearch(research FormattedResponse) {
-	fmt.Println(RenderResultView(research))
+	fmt.Println(RenderResultView(research, TerminalWidth()))
 }
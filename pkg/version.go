@@ -0,0 +1,18 @@
+package pkg
+
+import "runtime/debug"
+
+// Version is the photon release. Release builds set it with
+// -ldflags "-X github.com/Jacky040124/photon/pkg.Version=v1.2.3".
+var Version = "dev"
+
+// GetVersion returns the release version, falling back to the module version for go install builds
+func GetVersion() string {
+	if Version != "dev" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return Version
+}